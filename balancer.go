@@ -0,0 +1,216 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午5:40:00
+// @Desc api 配置与密钥的负载均衡：每次选择密钥 (含故障切换) 前由 Balancer 从可用的候选中选择一个
+package ai_sdk
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 负载均衡策略名称，用于配置文件的 balancer 配置项
+const (
+	BalancerFailover      = "failover"        // 按配置顺序使用，失败时切换下一个 (默认)
+	BalancerRoundRobin    = "round_robin"     // 轮询
+	BalancerWeighted      = "weighted"        // 按 api 配置的权重随机
+	BalancerLeastInFlight = "least_in_flight" // 进行中请求数最少
+	BalancerLatency       = "latency"         // 响应延迟 (EWMA) 最低
+	BalancerRandom        = "random"          // 随机
+)
+
+const latencyDecay = 0.3 // 延迟 EWMA 中最新一次请求的权重
+
+var balancerErr = errors.New("unknown balancer") // 未知的负载均衡策略
+
+// Candidate 可选择的 api 配置与密钥
+type Candidate struct {
+	Endpoint int           // ApiCfgList 中的下标
+	Key      int           // AuthList 中的下标
+	Weight   int           // api 配置的权重 (至少为 1)
+	InFlight int64         // 该密钥进行中的请求数
+	Latency  time.Duration // 该密钥响应延迟的 EWMA，0 表示尚无数据
+}
+
+// Balancer 负载均衡策略，Pick 返回所选候选的下标，candidates 按配置顺序排列且不为空
+// 同一密钥上的重试不会重新选择；Pick 会被并发调用
+type Balancer interface {
+	Pick(candidates []Candidate) int
+}
+
+// NewBalancer 根据名称创建负载均衡策略，名称为空时使用 failover
+func NewBalancer(name string) (Balancer, error) {
+	switch name {
+	case "", BalancerFailover:
+		return FailoverBalancer(), nil
+	case BalancerRoundRobin:
+		return RoundRobinBalancer(), nil
+	case BalancerWeighted:
+		return WeightedBalancer(), nil
+	case BalancerLeastInFlight:
+		return LeastInFlightBalancer(), nil
+	case BalancerLatency:
+		return LatencyBalancer(), nil
+	case BalancerRandom:
+		return RandomBalancer(), nil
+	}
+	return nil, fmt.Errorf("%w: %q", balancerErr, name)
+}
+
+// BalancerFunc 以函数实现 Balancer
+type BalancerFunc func(candidates []Candidate) int
+
+func (f BalancerFunc) Pick(candidates []Candidate) int {
+	return f(candidates)
+}
+
+// FailoverBalancer 总是选择第一个候选，即按配置顺序使用 api 配置与密钥
+func FailoverBalancer() Balancer {
+	return BalancerFunc(func(candidates []Candidate) int { return 0 })
+}
+
+// RoundRobinBalancer 轮询候选
+func RoundRobinBalancer() Balancer {
+	var next atomic.Uint64
+	return BalancerFunc(func(candidates []Candidate) int {
+		return int((next.Add(1) - 1) % uint64(len(candidates)))
+	})
+}
+
+// WeightedBalancer 按 api 配置的权重随机选择，同一 api 配置的密钥平分该配置的权重
+func WeightedBalancer() Balancer {
+	return BalancerFunc(func(candidates []Candidate) int {
+		keys := make(map[int]int, len(candidates)) // api 配置 -> 候选密钥数
+		for _, c := range candidates {
+			keys[c.Endpoint]++
+		}
+		weights := make([]float64, len(candidates))
+		var total float64
+		for i, c := range candidates {
+			weights[i] = float64(max(c.Weight, 1)) / float64(keys[c.Endpoint])
+			total += weights[i]
+		}
+		r := randFloat64() * total
+		for i, w := range weights {
+			if r < w {
+				return i
+			}
+			r -= w
+		}
+		return len(candidates) - 1
+	})
+}
+
+// LeastInFlightBalancer 选择进行中请求数最少的候选，数量相同时随机选择
+func LeastInFlightBalancer() Balancer {
+	return BalancerFunc(func(candidates []Candidate) int {
+		return pickMin(candidates, func(c Candidate) int64 { return c.InFlight })
+	})
+}
+
+// LatencyBalancer 选择响应延迟 (EWMA) 最低的候选，尚无延迟数据的候选优先，延迟相同时随机选择
+func LatencyBalancer() Balancer {
+	return BalancerFunc(func(candidates []Candidate) int {
+		return pickMin(candidates, func(c Candidate) int64 { return int64(c.Latency) })
+	})
+}
+
+// RandomBalancer 随机选择候选
+func RandomBalancer() Balancer {
+	return BalancerFunc(func(candidates []Candidate) int {
+		return randIntn(len(candidates))
+	})
+}
+
+// pickMin 选择 value 最小的候选，从随机位置开始比较以打散相同值的候选
+func pickMin(candidates []Candidate, value func(c Candidate) int64) int {
+	start := randIntn(len(candidates))
+	best := start
+	for k := 1; k < len(candidates); k++ {
+		i := (start + k) % len(candidates)
+		if value(candidates[i]) < value(candidates[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+func randIntn(n int) int {
+	return rand.Intn(n) // nolint:gosec 负载均衡无需安全随机数
+}
+
+func randFloat64() float64 {
+	return rand.Float64() // nolint:gosec 负载均衡无需安全随机数
+}
+
+// keyLoad 密钥的进行中请求数与响应延迟
+type keyLoad struct {
+	inFlight atomic.Int64
+	mu       sync.Mutex
+	latency  time.Duration // 等待响应头的时间的 EWMA
+}
+
+// observe 记录一次成功请求的延迟
+func (l *keyLoad) observe(d time.Duration) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.latency == 0 {
+		l.latency = d
+		return
+	}
+	l.latency = time.Duration(latencyDecay*float64(d) + (1-latencyDecay)*float64(l.latency))
+}
+
+func (l *keyLoad) snapshot() (inFlight int64, latency time.Duration) {
+	if l == nil {
+		return 0, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight.Load(), l.latency
+}
+
+// candidates 尚未尝试且未被隔离的 api 配置与密钥，tried 为已尝试的密钥，skipped 为已跳过的 api 配置
+func (a AIClient) candidates(tried map[[2]int]bool, skipped map[int]bool) []Candidate {
+	var candidates []Candidate
+	for i, apiCfg := range a.ApiCfgList {
+		if skipped[i] {
+			continue
+		}
+		ep := a.endpointAt(i)
+		for j := range apiCfg.AuthList {
+			if tried[[2]int{i, j}] {
+				continue
+			}
+			c := Candidate{Endpoint: i, Key: j, Weight: max(apiCfg.Weight, 1)}
+			if key := ep.key(j); key != nil {
+				if key.quarantine.quarantined() {
+					continue
+				}
+				c.InFlight, c.Latency = key.load.snapshot()
+			}
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// pick 由负载均衡策略选择候选，策略返回的下标越界时选择第一个
+func (a AIClient) pick(candidates []Candidate) Candidate {
+	balancer := a.balancer
+	if balancer == nil {
+		balancer = FailoverBalancer()
+	}
+	i := balancer.Pick(candidates)
+	if i < 0 || i >= len(candidates) {
+		i = 0
+	}
+	return candidates[i]
+}
@@ -0,0 +1,134 @@
+package ai_sdk
+
+import (
+	"errors"
+	"github.com/Clov614/go-ai-sdk/config"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewBalancer(t *testing.T) {
+	for _, name := range []string{"", BalancerFailover, BalancerRoundRobin, BalancerWeighted, BalancerLeastInFlight, BalancerLatency, BalancerRandom} {
+		if b, err := NewBalancer(name); err != nil || b == nil {
+			t.Errorf("NewBalancer(%q) = %v, %v", name, b, err)
+		}
+	}
+	if _, err := NewBalancer("fastest"); !errors.Is(err, balancerErr) {
+		t.Errorf("NewBalancer(unknown) error = %v, want balancerErr", err)
+	}
+}
+
+func TestBalancers(t *testing.T) {
+	three := []Candidate{{Endpoint: 0}, {Endpoint: 1}, {Endpoint: 2}}
+	tests := []struct {
+		name       string
+		balancer   Balancer
+		candidates []Candidate
+		want       []int // 依次调用 Pick 的期望结果
+	}{
+		{name: "failover", balancer: FailoverBalancer(), candidates: three, want: []int{0, 0, 0}},
+		{name: "round robin", balancer: RoundRobinBalancer(), candidates: three, want: []int{0, 1, 2, 0}},
+		{
+			name:     "least in flight",
+			balancer: LeastInFlightBalancer(),
+			candidates: []Candidate{
+				{Endpoint: 0, InFlight: 3}, {Endpoint: 1, InFlight: 1}, {Endpoint: 2, InFlight: 2},
+			},
+			want: []int{1, 1, 1},
+		},
+		{
+			name:     "latency prefers unmeasured",
+			balancer: LatencyBalancer(),
+			candidates: []Candidate{
+				{Endpoint: 0, Latency: time.Second}, {Endpoint: 1, Latency: 0}, {Endpoint: 2, Latency: time.Millisecond},
+			},
+			want: []int{1, 1},
+		},
+		{
+			name:     "latency lowest",
+			balancer: LatencyBalancer(),
+			candidates: []Candidate{
+				{Endpoint: 0, Latency: time.Second}, {Endpoint: 1, Latency: 300 * time.Millisecond}, {Endpoint: 2, Latency: time.Millisecond},
+			},
+			want: []int{2, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.balancer.Pick(tt.candidates); got != want {
+					t.Fatalf("Pick() #%d = %d, want %d", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWeightedBalancer(t *testing.T) {
+	// api 配置 0 权重 3 (两个密钥平分)，api 配置 1 权重 1
+	candidates := []Candidate{{Endpoint: 0, Key: 0, Weight: 3}, {Endpoint: 0, Key: 1, Weight: 3}, {Endpoint: 1, Key: 0, Weight: 1}}
+	b := WeightedBalancer()
+	counts := make([]int, len(candidates))
+	const n = 8000
+	for i := 0; i < n; i++ {
+		counts[b.Pick(candidates)]++
+	}
+	if share := float64(counts[0]+counts[1]) / n; share < 0.7 || share > 0.8 {
+		t.Errorf("weighted endpoint share = %.2f, want about 0.75 (counts %v)", share, counts)
+	}
+	if share := float64(counts[0]) / float64(counts[0]+counts[1]); share < 0.4 || share > 0.6 {
+		t.Errorf("keys of the same endpoint share = %.2f, want about 0.5 (counts %v)", share, counts)
+	}
+	for i := 0; i < 100; i++ {
+		if got := RandomBalancer().Pick(candidates); got < 0 || got >= len(candidates) {
+			t.Fatalf("RandomBalancer.Pick() = %d out of range", got)
+		}
+	}
+}
+
+func TestAIClient_Balancer(t *testing.T) {
+	var mu sync.Mutex
+	var auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auths = append(auths, r.Header.Get("Authorization"))
+		mu.Unlock()
+		if r.Header.Get("Authorization") == "Bearer sk-broken" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(okBody))
+	}))
+	defer server.Close()
+	a, err := NewClient([]config.APIConfig{{Url: server.URL, AuthList: []string{"sk-a", "sk-b", "sk-broken"}}},
+		WithBalancer(RoundRobinBalancer()))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err = a.Send(Request{Messages: []Message{{Role: userRole, Content: "hi"}}}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	// 第三次请求轮询到 sk-broken 失败后，由负载均衡策略在剩余的密钥中重新选择
+	want := []string{"Bearer sk-a", "Bearer sk-b", "Bearer sk-broken", "Bearer sk-b"}
+	if len(auths) != len(want) {
+		t.Fatalf("requests = %v, want %v", auths, want)
+	}
+	for i := range want {
+		if auths[i] != want[i] {
+			t.Errorf("request %d auth = %s, want %s", i, auths[i], want[i])
+		}
+	}
+	for _, key := range a.Health()[0].Keys {
+		if key.InFlight != 0 {
+			t.Errorf("key %s in flight = %d, want 0 after response closed", key.Key, key.InFlight)
+		}
+	}
+	if a.Health()[0].Keys[0].Latency <= 0 {
+		t.Errorf("key latency not recorded")
+	}
+}
@@ -6,13 +6,13 @@ package ai_sdk
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/Clov614/go-ai-sdk/config"
 	"github.com/rs/zerolog/log"
 	"io"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 )
@@ -20,47 +20,50 @@ import (
 var aiclient *AIClient
 
 type AIClient struct {
-	ContentType string
-	Model       string
-	ApiCfgList  []config.APIConfig
-	client      *http.Client
-	timeout     int
-	EndPoint    string
+	ContentType     string
+	Model           string
+	ApiCfgList      []config.APIConfig
+	client          *http.Client
+	timeout         time.Duration
+	customTransport bool              // 使用调用方提供的 Transport，不再按 ProxyAddr 设置代理
+	endpoints       []endpoint        // 与 ApiCfgList 一一对应的 http.Client，构造时创建
+	header          http.Header       // 每个请求附带的额外请求头
+	retry           RetryPolicy       // 单个密钥的重试策略
+	balancer        Balancer          // api 配置与密钥的负载均衡策略
+	rateLimitWait   time.Duration     // 所有密钥均达到客户端限流时的最长等待时间
+	tokenLimited    bool              // 存在设置了 TPM 的密钥，请求前需要预估 token 数
+	onQuarantine    KeyQuarantineFunc // 密钥被隔离时的回调
+	breaker         BreakerPolicy     // api 地址与密钥的熔断策略
+	EndPoint        string
+	Params          config.ChatParams // 默认生成参数 (优先级最低)
 }
 
-// NewAIClient 创建一个自定义请求客户端
+// NewAIClient 创建一个自定义请求客户端，timeout 单位为秒
+// 为兼容旧版本，timeout 小于 10 时静默使用 10，且不校验 api 配置；该行为仅保留给旧代码
+//
+// Deprecated: 使用 NewClient，其以 WithTimeout 设置任意超时并在配置不合法时返回错误
 func NewAIClient(apiCfgList []config.APIConfig, model string, endPoint string, timeout int) *AIClient {
-	client := AIClient{
-		ContentType: config.DefaultContentType,
-		Model:       model,
-		ApiCfgList:  apiCfgList,
-		EndPoint:    endPoint,
-	}
 	if timeout < 10 {
-		client.timeout = 10
+		timeout = 10
+	}
+	client := AIClient{
+		ContentType:   config.DefaultContentType,
+		Model:         model,
+		ApiCfgList:    apiCfgList,
+		EndPoint:      endPoint,
+		timeout:       time.Duration(timeout) * time.Second,
+		retry:         DefaultRetryPolicy(),
+		breaker:       DefaultBreakerPolicy(),
+		balancer:      FailoverBalancer(),
+		rateLimitWait: config.DefaultRateLimitWait * time.Second,
 	}
 	client.client = &http.Client{
-		Timeout: time.Duration(client.timeout) * time.Second,
+		Timeout: client.timeout,
 	}
+	client.buildEndpoints()
 	return &client
 }
 
-// 根据 proxy 字符串 设置请求代理
-func (a AIClient) transformProxy(proxyAddr string) {
-	if proxyAddr != "" {
-		proxy, err := url.Parse(ensurePrefix(proxyAddr))
-		if err != nil {
-			log.Error().Err(err).Str("proxyAddr", proxyAddr).Msg("invalid proxy URL")
-			return
-		}
-		a.client.Transport = &http.Transport{
-			Proxy: http.ProxyURL(proxy),
-		}
-	} else {
-		a.client.Transport = nil // 无代理
-	}
-}
-
 // ensureBearer 检查并添加Bearer前缀
 func ensureBearer(auth string) string {
 	if strings.HasPrefix(auth, "Bearer ") {
@@ -82,7 +85,12 @@ func ensurePrefix(address string) string {
 }
 
 func (a AIClient) Send(req Request) (resp Response[DefalutResponse], err error) {
-	resp, err = doSend[DefalutResponse](a, a.convertReq(req))
+	return a.SendWithContext(context.Background(), req)
+}
+
+// SendWithContext 携带 context 发起请求，ctx 取消或超时将中断请求以及后续的密钥重试
+func (a AIClient) SendWithContext(ctx context.Context, req Request) (resp Response[DefalutResponse], err error) {
+	resp, err = doSend[DefalutResponse](ctx, a, a.convertReq(req))
 	if err != nil {
 		return resp, fmt.Errorf("send incremental response failed: %w", err)
 	}
@@ -96,153 +104,389 @@ func (a AIClient) Send(req Request) (resp Response[DefalutResponse], err error)
 // SendByFuncCall  使用 Send默认调用就可以支持 Function_Call
 // deprecated
 func (a AIClient) SendByFuncCall(req Request) (resp Response[FunctionCallResponse], err error) {
-	resp, err = doSend[FunctionCallResponse](a, a.convertReq(req))
+	resp, err = doSend[FunctionCallResponse](context.Background(), a, a.convertReq(req))
 	if err != nil {
 		return resp, fmt.Errorf("send functioncall response error: %w", err)
 	}
 	return resp, nil
 }
 
+// convertReq 转换为接口请求，生成参数按 AIClient.Params (config.AICfg) < 会话默认参数 < Request 的优先级逐个覆盖
 func (a AIClient) convertReq(req Request) ChatCompletionRequest {
 	if req.Tools != nil && req.ToolChoice == "" {
 		req.ToolChoice = "auto"
 	}
+	params := a.Params.Merge(req.defaults).Merge(req.ChatParams)
+	if req.Tools == nil || len(*req.Tools) == 0 {
+		params.ParallelToolCalls = nil // 未携带 tools 时接口不允许设置该参数
+	}
+	model := a.Model
+	if req.Model != "" {
+		model = req.Model
+	}
 	return ChatCompletionRequest{
-		Model:      a.Model,
+		Model:      model,
 		Messages:   req.Messages,
 		Tools:      req.Tools,
 		ToolChoice: req.ToolChoice,
+		ChatParams: params,
+	}
+}
+
+func doSend[T DefalutResponse | FunctionCallResponse](ctx context.Context, a AIClient, request ChatCompletionRequest) (response Response[T], err error) {
+	resp, baseResp, err := doRequest(ctx, a, request)
+	response.baseResp = baseResp
+	if err != nil {
+		return response, err
+	}
+	defer resp.Body.Close()
+	// 正常处理响应
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return response, fmt.Errorf("doSend ReadAll(resp.body): %w", err)
+	}
+	// nolint
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return response, fmt.Errorf("doSend json.Unmarshal(body, &response): %w", err)
+	}
+	if response.ID == "" { // 部分中转以 200 状态码返回 {"error": {...}}
+		response.err = parseRespError(body)
 	}
+	var data T
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return response, fmt.Errorf("doSend json.Unmarshal(body, &data): %w", err)
+	}
+	response.data = data
+	var usage struct {
+		Usage *Usage `json:"usage"`
+	}
+	if json.Unmarshal(body, &usage) == nil {
+		reportUsage(resp.Body, usage.Usage)
+	}
+	return response, nil
 }
 
-func doSend[T DefalutResponse | FunctionCallResponse](a AIClient, request ChatCompletionRequest) (response Response[T], err error) {
+// doRequest 由负载均衡策略依次选择 api 配置与密钥发起请求，返回第一个状态码为 200 的响应（调用方负责关闭 Body）
+// 每个密钥按重试策略重试 429、5xx、连接重置与超时，重试次数用尽或遇到其他错误后切换下一个密钥
+// 熔断中的 api 地址与密钥会被跳过，达到客户端限流的密钥在其余密钥可用时跳过，均不可用时等待
+func doRequest(parent context.Context, a AIClient, request ChatCompletionRequest) (resp *http.Response, baseResp BaseResponse, err error) {
 	// 构造请求body
 	body, err := json.Marshal(request)
 	if err != nil {
-		return response, fmt.Errorf("ChatCompletionRequest marshalling failed: %w", err)
+		return nil, baseResp, fmt.Errorf("ChatCompletionRequest marshalling failed: %w", err)
 	}
 
-	// 循环重试发送请求
-	var req *http.Request
-	var resp *http.Response
-
-apiCfgLoop:
-	for _, apiCfg := range a.ApiCfgList {
-
-		for _, auth := range apiCfg.AuthList {
-			// 设置请求的req
-			req, err = http.NewRequest(http.MethodPost, apiCfg.Url+a.EndPoint, bytes.NewBuffer(body))
-			if err != nil {
-				log.Error().Err(err).Msg("new request failed")
-				continue
-			}
-			// 转换代理并设置
-			a.transformProxy(apiCfg.ProxyAddr)
-			req.Header.Set("Content-Type", a.ContentType)
-			// 根据auth尝试进行请求
-			req.Header.Set("Authorization", ensureBearer(auth))
-			resp, err = a.client.Do(req) // nolint:bodyclose
-			if err != nil {
-				log.Error().Err(err).Msg("send ai talk request failed")
-				continue
-			}
-			// 根据状态码处理响应
-			if statusCode := resp.StatusCode; statusCode == http.StatusOK {
-				err = nil                          // 错误置空
-				response.baseResp = BaseResponse{} // 错误置空
-				break apiCfgLoop
+	var lastErr error
+	var tokens int
+	if a.tokenLimited {
+		tokens = estimateRequestTokens(request)
+	}
+	waitDeadline := time.Now().Add(a.rateLimitWait)
+	tried, skipped := make(map[[2]int]bool), make(map[int]bool)
+	for i, apiCfg := range a.ApiCfgList {
+		if ep := a.endpointAt(i); ep != nil && ep.err != nil {
+			log.Error().Err(ep.err).Str("url", apiCfg.Url).Msg("skip api config with invalid transport")
+			skipped[i] = true
+		}
+	}
+	for {
+		candidates := a.candidates(tried, skipped)
+		if len(candidates) == 0 {
+			break
+		}
+		if parent.Err() != nil { // 调用方已取消，不再尝试其余密钥
+			return nil, baseResp, fmt.Errorf("request canceled: %w", parent.Err())
+		}
+		ready, wait := a.ready(candidates, tokens, time.Now())
+		if len(ready) == 0 { // 所有密钥均达到客户端限流
+			if remaining := time.Until(waitDeadline); remaining < wait {
+				lastErr = fmt.Errorf("%w: wait %s", localRateLimitErr, wait)
+				break
 			}
-			// 打印错误信息保存错误
-			switch resp.StatusCode {
-			case http.StatusUnauthorized:
-				err = fmt.Errorf("api: %s, %w", apiCfg.Url, unAuthErr)
-				response.baseResp = BaseResponse{
-					Ret:    authorizationError,
-					ErrMsg: "401 Authorization Required",
-				}
-			case http.StatusMethodNotAllowed:
-				err = fmt.Errorf("api: %s, %w", apiCfg.Url, methodNotAllowedErr)
-				log.Error().Err(paramUnSupportError).Fields(map[string]interface{}{
-					"request":  request,
-					"url":      apiCfg.Url,
-					"EndPoint": a.EndPoint,
-				}).Msg(resp.Status)
-				response.baseResp = BaseResponse{
-					Ret:    authorizationError,
-					ErrMsg: "405 Not Allowed",
-				}
-			default:
-				// 处理其他未预期的状态码
-				// nolint
-				err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-				log.Error().Err(err).Fields(map[string]interface{}{
-					"request":  request,
-					"url":      apiCfg.Url,
-					"EndPoint": a.EndPoint,
-				}).Msg(resp.Status)
-				response.baseResp = BaseResponse{
-					Ret:    authorizationError,
-					ErrMsg: fmt.Sprintf("%d Not Allowed", resp.StatusCode),
-				}
+			if !sleepContext(parent, wait) {
+				return nil, baseResp, fmt.Errorf("request canceled: %w", parent.Err())
 			}
-			resp.Body.Close()
-			resp = nil
+			continue
 		}
-	}
-	if resp == nil {
-		response.baseResp = BaseResponse{
-			Ret:    paramUnSupportError,
-			ErrMsg: "返回值为空，请检查配置文件设置项是否正确填写",
+		c := a.pick(ready)
+		res, skipEndpoint, limited := a.tryKey(parent, c, request, body, tokens)
+		if limited { // 额度被并发的请求占用，重新选择
+			continue
+		}
+		if res.resp != nil {
+			return res.resp, BaseResponse{}, nil
+		}
+		if parent.Err() != nil {
+			return nil, baseResp, fmt.Errorf("request canceled: %w", parent.Err())
+		}
+		lastErr = res.err
+		tried[[2]int{c.Endpoint, c.Key}] = true
+		if skipEndpoint {
+			skipped[c.Endpoint] = true
 		}
-		return response, fmt.Errorf("response empty err: %w", configErr)
 	}
-	if resp != nil {
-		defer resp.Body.Close()
+	baseResp = BaseResponse{
+		Ret:    paramUnSupportError,
+		ErrMsg: "返回值为空，请检查配置文件设置项是否正确填写",
 	}
+	if lastErr == nil && a.quarantinedKeys() > 0 {
+		lastErr = keyQuarantinedErr
+	}
+	if lastErr != nil {
+		return nil, baseResp, fmt.Errorf("response empty err: %w: %w", configErr, lastErr)
+	}
+	return nil, baseResp, fmt.Errorf("response empty err: %w", configErr)
+}
+
+// tryKey 使用候选的 api 配置与密钥请求，按重试策略重试；skipEndpoint 表示该 api 地址熔断中，其余密钥无需再尝试
+// limited 表示首次请求前密钥已达到客户端限流，未发出请求
+func (a AIClient) tryKey(parent context.Context, c Candidate, request ChatCompletionRequest, body []byte,
+	tokens int) (res attemptResult, skipEndpoint bool, limited bool) {
+	apiCfg := a.ApiCfgList[c.Endpoint]
+	auth := apiCfg.AuthList[c.Key]
+	ep := a.endpointAt(c.Endpoint)
+	// 流式请求的响应体读取时间不可预期，不能使用 client 的整体超时
+	client, err := a.httpClient(c.Endpoint, request.Stream)
 	if err != nil {
-		return response, fmt.Errorf("all requests failed %w", err)
+		res.err = err
+		return res, true, false
 	}
-	// 正常处理响应
-	body, err = io.ReadAll(resp.Body)
+	key := ep.key(c.Key)
+	for attempt := 1; ; attempt++ {
+		if parent.Err() != nil {
+			return res, false, false
+		}
+		if ok, endpointOpen := ep.admit(c.Key, a.breaker, time.Now()); !ok {
+			if endpointOpen {
+				log.Debug().Str("url", apiCfg.Url).Msg("skip api in circuit breaker open state")
+				if res.err == nil {
+					res.err = fmt.Errorf("api: %s, %w", apiCfg.Url, circuitOpenErr)
+				}
+				return res, true, false
+			}
+			log.Debug().Str("url", apiCfg.Url).Str("key", maskKey(auth)).Msg("skip key in circuit breaker open state")
+			if res.err == nil {
+				res.err = fmt.Errorf("api: %s, key %s, %w", apiCfg.Url, maskKey(auth), circuitOpenErr)
+			}
+			return res, false, false
+		}
+		lease, ok := key.acquire(time.Now(), tokens)
+		if !ok {
+			ep.release(c.Key, a.breaker, time.Now())
+			if attempt == 1 {
+				return res, false, true
+			}
+			log.Debug().Str("url", apiCfg.Url).Str("key", maskKey(auth)).Msg("stop retrying key in client-side rate limit")
+			return res, false, false
+		}
+		res = a.sendOnce(parent, client, apiCfg, auth, lease, request, body)
+		ep.record(apiCfg.Url, c.Key, a.breaker, res, parent.Err() != nil, time.Now())
+		if res.quarantine != "" {
+			a.quarantineKey(c.Endpoint, c.Key, key, res.quarantine, res.apiErr.Message)
+		}
+		if res.resp != nil || parent.Err() != nil || !res.retryable || attempt >= a.retry.attempts() {
+			return res, false, false
+		}
+		delay, ok := a.retry.wait(attempt, res.retryAfter)
+		if !ok {
+			log.Warn().Err(res.err).Str("url", apiCfg.Url).Dur("retryAfter", delay).Msg("retry-after exceeds max delay, try next key")
+			return res, false, false
+		}
+		log.Warn().Err(res.err).Str("url", apiCfg.Url).Int("attempt", attempt).Dur("delay", delay).Msg("retry ai talk request")
+		if !sleepContext(parent, delay) {
+			return res, false, false
+		}
+	}
+}
+
+// attemptResult 单次请求的结果
+type attemptResult struct {
+	resp       *http.Response // 状态码为 200 的响应，失败时为 nil
+	status     int            // 响应状态码，网络错误时为 0
+	apiErr     RespError      // 错误响应体中的错误信息
+	quarantine string         // 需要隔离密钥时为隔离原因
+	baseResp   BaseResponse
+	err        error
+	retryable  bool          // 可在同一密钥上重试
+	retryAfter time.Duration // 接口要求的等待时间 (Retry-After / x-ratelimit-reset-*)
+}
+
+// sendOnce 使用指定的 api 与密钥请求一次，lease 为该密钥占用的额度 (可为 nil)，请求结束时归还
+func (a AIClient) sendOnce(parent context.Context, client *http.Client, apiCfg config.APIConfig, auth string, lease *keyLease,
+	request ChatCompletionRequest, body []byte) (res attemptResult) {
+	// 流式请求仅对等待响应头的阶段计时
+	ctx, cancel := context.WithCancel(parent)
+	// 设置请求的req
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiCfg.Url+a.EndPoint, bytes.NewReader(body))
 	if err != nil {
-		return response, fmt.Errorf("doSend ReadAll(resp.body): %w", err)
+		cancel()
+		lease.release()
+		log.Error().Err(err).Msg("new request failed")
+		res.err = err
+		return res
+	}
+	for key, values := range a.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", a.ContentType)
+	if request.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	// 根据auth尝试进行请求
+	req.Header.Set("Authorization", ensureBearer(auth))
+	var headerTimer *time.Timer
+	if timeout := a.requestTimeout(); request.Stream && timeout > 0 {
+		headerTimer = time.AfterFunc(timeout, cancel)
+	}
+	start := time.Now()
+	resp, err := client.Do(req) // nolint:bodyclose
+	if headerTimer != nil {
+		headerTimer.Stop()
 	}
-	// nolint
-	err = json.Unmarshal(body, &response)
 	if err != nil {
-		return response, fmt.Errorf("doSend json.Unmarshal(body, &response): %w", err)
+		lease.release()
+		cancel()
+		// 等待响应头超时 (ctx 被计时器取消) 与网络错误同样可重试
+		res.err = fmt.Errorf("api: %s, %w: %w", apiCfg.Url, networkErr, err)
+		res.retryable = parent.Err() == nil && (ctx.Err() != nil || retryableError(err))
+		if parent.Err() == nil {
+			log.Error().Err(err).Msg("send ai talk request failed")
+		}
+		return res
+	}
+	// 根据状态码处理响应
+	if resp.StatusCode == http.StatusOK {
+		lease.observe(time.Since(start))
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel, lease: lease} // 流式响应读取完毕后才归还额度
+		res.resp = resp
+		return res
 	}
-	if response.ID == "" {
-		var respErr RespError
-		err := json.Unmarshal(body, &respErr)
-		if err != nil {
-			return response, fmt.Errorf("doSend json.Unmarshal(body, &respErr): %w", err)
+	// 打印错误信息保存错误
+	res.status = resp.StatusCode
+	errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxDrainBytes)) // 读完剩余响应体以复用连接
+	res.apiErr = parseRespError(errBody)
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		res.err = fmt.Errorf("api: %s, %w", apiCfg.Url, unAuthErr)
+		res.baseResp = BaseResponse{
+			Ret:    authorizationError,
+			ErrMsg: "401 Authorization Required",
+		}
+	case http.StatusMethodNotAllowed:
+		res.err = fmt.Errorf("api: %s, %w", apiCfg.Url, methodNotAllowedErr)
+		log.Error().Err(paramUnSupportError).Fields(map[string]interface{}{
+			"request":  request,
+			"url":      apiCfg.Url,
+			"EndPoint": a.EndPoint,
+		}).Msg(resp.Status)
+		res.baseResp = BaseResponse{
+			Ret:    authorizationError,
+			ErrMsg: "405 Not Allowed",
+		}
+	default:
+		// 处理其他未预期的状态码
+		// nolint
+		switch {
+		case res.apiErr.RawCode == QuarantineInsufficientQuota || res.apiErr.Type == QuarantineInsufficientQuota:
+			res.err = fmt.Errorf("api: %s, %w", apiCfg.Url, quotaExceededErr)
+		case resp.StatusCode == http.StatusTooManyRequests:
+			res.err = fmt.Errorf("api: %s, %w", apiCfg.Url, rateLimitErr)
+		case resp.StatusCode >= http.StatusInternalServerError:
+			res.err = fmt.Errorf("api: %s, %w: status code %d", apiCfg.Url, serverErr, resp.StatusCode)
+		default:
+			res.err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+		res.retryable = retryableStatus(resp.StatusCode)
+		if res.retryable {
+			res.retryAfter = retryAfter(resp.Header, time.Now())
+		}
+		log.Error().Err(res.err).Fields(map[string]interface{}{
+			"request":  request,
+			"url":      apiCfg.Url,
+			"EndPoint": a.EndPoint,
+		}).Msg(resp.Status)
+		res.baseResp = BaseResponse{
+			Ret:    authorizationError,
+			ErrMsg: fmt.Sprintf("%d Not Allowed", resp.StatusCode),
 		}
-		response.err = respErr
 	}
-	var data T
-	err = json.Unmarshal(body, &data)
-	if err != nil {
-		return response, fmt.Errorf("doSend json.Unmarshal(body, &data): %w", err)
+	if res.apiErr.Message != "" {
+		res.err = fmt.Errorf("%w: %s", res.err, res.apiErr.Message)
 	}
-	response.data = data
-	return response, nil
+	if res.quarantine = quarantineReason(resp.StatusCode, res.apiErr); res.quarantine != "" {
+		res.retryable = false // 额度用尽、账户停用等重试无意义
+	}
+	resp.Body.Close()
+	lease.release()
+	cancel()
+	return res
 }
 
-func init() {
-	aiclient = &AIClient{
+const maxDrainBytes = 4 << 10 // 错误响应最多读取的字节数，超出时不复用连接
+
+// cancelBody 关闭响应体时一并释放请求的 context 与密钥额度
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+	lease  *keyLease
+}
 
-		ContentType: config.Config.ContentType,
-		Model:       config.Config.Model,
-		ApiCfgList:  config.Config.ApiCfgs,
-		EndPoint:    config.Config.EndPoint,
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	b.lease.release()
+	return err
+}
+
+// requestTimeout 单次请求超时时间 (0 表示不限制)
+func (a AIClient) requestTimeout() time.Duration {
+	if a.client != nil && a.client.Timeout > 0 {
+		return a.client.Timeout
+	}
+	return a.timeout
+}
+
+// newClientFromConfig 根据配置创建客户端，与 NewClient 使用相同的校验，配置不合法时返回错误
+// timeout 为 0 时使用 config.DefaultTimeout，其余未设置的配置项同样使用默认值；opts 在配置之后生效
+func newClientFromConfig(cfg config.AICfg, opts ...ClientOption) (*AIClient, error) {
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("%w: negative timeout %ds", clientOptionErr, cfg.Timeout)
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = config.DefaultTimeout
+	}
+	balancer, err := NewBalancer(cfg.Balancer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", clientOptionErr, err)
+	}
+	client, err := NewClient(cfg.ApiCfgs, append([]ClientOption{
+		WithModel(cfg.Model),
+		WithEndPoint(cfg.EndPoint),
+		WithTimeout(time.Duration(timeout) * time.Second),
+		WithRetryPolicy(retryPolicyFromConfig(cfg.Retry)),
+		WithBreakerPolicy(breakerPolicyFromConfig(cfg.Breaker)),
+		WithBalancer(balancer),
+		WithRateLimitWait(rateLimitWaitFromConfig(cfg.RateLimitWait)),
+		WithClientParams(cfg.Params),
+	}, opts...)...)
+	if err != nil {
+		return nil, err
 	}
-	if config.Config.Timeout < 10 {
-		aiclient.timeout = 10
-	} else {
-		aiclient.timeout = config.Config.Timeout
+	if cfg.ContentType != "" {
+		client.ContentType = cfg.ContentType
 	}
-	aiclient.client = &http.Client{
-		Timeout: time.Duration(aiclient.timeout) * time.Second,
+	return client, nil
+}
+
+func init() {
+	// 默认配置不含 api 密钥，无法通过校验；此时使用旧版构造的客户端占位，ai_sdk.New / Init 会替换
+	client, err := newClientFromConfig(config.Config)
+	if err != nil {
+		c := config.Config
+		client = NewAIClient(c.ApiCfgs, c.Model, c.EndPoint, c.Timeout)
 	}
+	aiclient = client
 }
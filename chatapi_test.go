@@ -5,10 +5,16 @@
 package ai_sdk
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"github.com/Clov614/go-ai-sdk/config"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestAIClient_SendByFuncCall(t *testing.T) {
@@ -158,7 +164,7 @@ func TestAIClient_Send(t *testing.T) {
 				Model:       tt.fields.Model,
 				ApiCfgList:  tt.fields.ApiCfgList,
 				client:      tt.fields.client,
-				timeout:     tt.fields.timeout,
+				timeout:     time.Duration(tt.fields.timeout) * time.Second,
 			}
 			gotResp, err := a.Send(tt.args.req)
 			if (err != nil) != tt.wantErr {
@@ -171,3 +177,81 @@ func TestAIClient_Send(t *testing.T) {
 		})
 	}
 }
+
+func TestAIClient_SendWithContext(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release // 阻塞直到测试结束
+	}))
+	defer server.Close()
+	defer close(release)
+	a := NewAIClient([]config.APIConfig{{Url: server.URL, AuthList: []string{"sk-1", "sk-2"}}}, config.DefaultModel, config.DefaultEndPoint, 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := a.SendWithContext(ctx, Request{Messages: []Message{{Role: userRole, Content: "hi"}}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SendWithContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("SendWithContext() returned after %v, want prompt return", elapsed)
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("server hits = %d, want 1 (no retry after cancel)", n)
+	}
+}
+
+func TestAIClient_convertReq(t *testing.T) {
+	a := AIClient{
+		Model: config.DefaultModel,
+		Params: config.ChatParams{
+			Temperature: Ptr(1.0),
+			TopP:        Ptr(0.9),
+			User:        "cfg",
+		},
+	}
+	tools := &[]Tool{{Type: defaultFuncType, Function: Function{Name: "f"}}}
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			name: "config defaults only",
+			req:  Request{},
+			want: `{"model":"gpt-4o-mini","messages":null,"temperature":1,"top_p":0.9,"user":"cfg"}`,
+		},
+		{
+			name: "request overrides session overrides config",
+			req: Request{
+				ChatParams: config.ChatParams{Temperature: Ptr(0.0), Stop: []string{"\n"}},
+				defaults:   config.ChatParams{Temperature: Ptr(0.5), TopP: Ptr(0.1), Seed: Ptr(7)},
+			},
+			want: `{"model":"gpt-4o-mini","messages":null,"temperature":0,"top_p":0.1,"stop":["\n"],"seed":7,"user":"cfg"}`,
+		},
+		{
+			name: "parallel_tool_calls dropped without tools",
+			req:  Request{ChatParams: config.ChatParams{ParallelToolCalls: Ptr(false)}},
+			want: `{"model":"gpt-4o-mini","messages":null,"temperature":1,"top_p":0.9,"user":"cfg"}`,
+		},
+		{
+			name: "parallel_tool_calls kept with tools",
+			req:  Request{Tools: tools, ChatParams: config.ChatParams{ParallelToolCalls: Ptr(false)}},
+			want: `{"model":"gpt-4o-mini","messages":null,"tools":[{"type":"function","function":{"name":"f","description":"","parameters":{"type":"","properties":null,"required":null},"strict":false}}],"tool_choice":"auto","temperature":1,"top_p":0.9,"user":"cfg","parallel_tool_calls":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(a.convertReq(tt.req))
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("convertReq() = %s\n want %s", got, tt.want)
+			}
+		})
+	}
+}
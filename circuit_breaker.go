@@ -0,0 +1,275 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午5:20:00
+// @Desc api 地址与密钥的健康统计与熔断：连续失败达到阈值后熔断并跳过，冷却结束后放行探测请求，成功则恢复
+package ai_sdk
+
+import (
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/config"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 正常
+	BreakerOpen                         // 熔断中，请求时跳过
+	BreakerHalfOpen                     // 冷却结束，放行少量探测请求
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// BreakerPolicy 熔断策略，api 地址与每个密钥各自使用一个熔断器
+// api 地址在网络错误、超时与 5xx 时计为失败，密钥在 401、403、429 时计为失败
+type BreakerPolicy struct {
+	FailureThreshold int           // 连续失败次数达到该值时熔断，小于 1 时不启用熔断
+	Cooldown         time.Duration // 熔断持续时间，结束后进入半开状态
+	HalfOpenProbes   int           // 半开状态下同时放行的探测请求数，小于 1 时按 1 处理
+}
+
+// DefaultBreakerPolicy 默认熔断策略：连续失败 5 次熔断 30s，之后放行 1 个探测请求
+func DefaultBreakerPolicy() BreakerPolicy {
+	return breakerPolicyFromConfig(config.BreakerCfg{})
+}
+
+// breakerPolicyFromConfig 根据配置创建熔断策略，未设置的配置项使用默认值，failure_threshold 小于 0 时不启用
+func breakerPolicyFromConfig(cfg config.BreakerCfg) BreakerPolicy {
+	if cfg.FailureThreshold == 0 {
+		cfg.FailureThreshold = config.DefaultBreakerThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = config.DefaultBreakerCooldown
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	return BreakerPolicy{
+		FailureThreshold: max(cfg.FailureThreshold, 0),
+		Cooldown:         time.Duration(cfg.Cooldown) * time.Second,
+		HalfOpenProbes:   cfg.HalfOpenProbes,
+	}
+}
+
+func (p BreakerPolicy) validate() error {
+	if p.Cooldown < 0 {
+		return fmt.Errorf("%w: negative breaker cooldown", clientOptionErr)
+	}
+	return nil
+}
+
+func (p BreakerPolicy) enabled() bool {
+	return p.FailureThreshold >= 1
+}
+
+func (p BreakerPolicy) probes() int {
+	return max(p.HalfOpenProbes, 1)
+}
+
+// HealthStats 熔断器的状态与统计
+type HealthStats struct {
+	State               BreakerState
+	ConsecutiveFailures int       // 连续失败次数
+	Requests            int64     // 计入统计的请求数
+	Failures            int64     // 失败的请求数
+	LastError           string    // 最近一次失败的错误信息
+	LastFailure         time.Time // 最近一次失败的时间
+	LastSuccess         time.Time // 最近一次成功的时间
+	OpenedAt            time.Time // 最近一次熔断的时间
+}
+
+// EndpointHealth api 地址及其密钥的健康状态
+type EndpointHealth struct {
+	Url string
+	HealthStats
+	Keys []KeyHealth
+}
+
+// KeyHealth 密钥的健康状态
+type KeyHealth struct {
+	Key string // 脱敏后的密钥
+	HealthStats
+	InFlight int64         // 进行中的请求数
+	Latency  time.Duration // 响应延迟的 EWMA
+}
+
+// outcome 单次请求对熔断器的影响
+type outcome int
+
+const (
+	outcomeIgnore  outcome = iota // 不计入统计 (请求被取消、错误与该熔断器无关)
+	outcomeSuccess                // 成功
+	outcomeFailure                // 失败
+)
+
+// breaker 熔断器，零值为正常状态
+type breaker struct {
+	mu     sync.Mutex
+	stats  HealthStats
+	probes int // 半开状态下进行中的探测请求数
+}
+
+// allow 是否放行请求，半开状态下放行的请求占用一个探测名额，需通过 record 归还
+func (b *breaker) allow(p BreakerPolicy, now time.Time) bool {
+	if b == nil || !p.enabled() {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.stats.State {
+	case BreakerOpen:
+		if now.Sub(b.stats.OpenedAt) < p.Cooldown {
+			return false
+		}
+		b.stats.State, b.probes = BreakerHalfOpen, 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= p.probes() {
+			return false
+		}
+		b.probes++
+	}
+	return true
+}
+
+// record 记录请求结果，返回记录前后的状态
+func (b *breaker) record(p BreakerPolicy, o outcome, err error, now time.Time) (from BreakerState, to BreakerState) {
+	if b == nil {
+		return BreakerClosed, BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	from = b.stats.State
+	if from == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+	switch o {
+	case outcomeSuccess:
+		b.stats.Requests++
+		b.stats.ConsecutiveFailures = 0
+		b.stats.LastSuccess = now
+		if from == BreakerHalfOpen {
+			b.stats.State = BreakerClosed
+		}
+	case outcomeFailure:
+		b.stats.Requests++
+		b.stats.Failures++
+		b.stats.ConsecutiveFailures++
+		b.stats.LastFailure = now
+		if err != nil {
+			b.stats.LastError = err.Error()
+		}
+		if p.enabled() && (from == BreakerHalfOpen || (from == BreakerClosed && b.stats.ConsecutiveFailures >= p.FailureThreshold)) {
+			b.stats.State, b.stats.OpenedAt = BreakerOpen, now
+		}
+	}
+	return from, b.stats.State
+}
+
+// snapshot 当前状态，冷却已结束的熔断器显示为半开
+func (b *breaker) snapshot(p BreakerPolicy, now time.Time) HealthStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.stats
+	if stats.State == BreakerOpen && now.Sub(stats.OpenedAt) >= p.Cooldown {
+		stats.State = BreakerHalfOpen
+	}
+	return stats
+}
+
+// admit 检查 api 地址与第 j 个密钥的熔断器，不放行时 endpointOpen 表示整个 api 地址被熔断
+func (ep *endpoint) admit(j int, p BreakerPolicy, now time.Time) (ok bool, endpointOpen bool) {
+	if ep == nil {
+		return true, false
+	}
+	if !ep.health.allow(p, now) {
+		return false, true
+	}
+	if key := ep.key(j); key != nil && !key.health.allow(p, now) {
+		ep.health.record(p, outcomeIgnore, nil, now) // 归还 api 地址的探测名额
+		return false, false
+	}
+	return true, false
+}
+
+// release 归还 admit 占用的探测名额 (请求未发出)
+func (ep *endpoint) release(j int, p BreakerPolicy, now time.Time) {
+	if ep == nil {
+		return
+	}
+	ep.health.record(p, outcomeIgnore, nil, now)
+	if key := ep.key(j); key != nil {
+		key.health.record(p, outcomeIgnore, nil, now)
+	}
+}
+
+// record 记录第 j 个密钥的请求结果，canceled 表示调用方已取消请求
+func (ep *endpoint) record(url string, j int, p BreakerPolicy, res attemptResult, canceled bool, now time.Time) {
+	if ep == nil {
+		return
+	}
+	endpointOutcome, keyOutcome := res.outcomes(canceled)
+	if from, to := ep.health.record(p, endpointOutcome, res.err, now); from != to {
+		log.Warn().Str("url", url).Stringer("from", from).Stringer("to", to).Err(res.err).Msg("api circuit breaker state changed")
+	}
+	if key := ep.key(j); key != nil {
+		if from, to := key.health.record(p, keyOutcome, res.err, now); from != to {
+			log.Warn().Str("url", url).Str("key", maskKey(key.auth)).Stringer("from", from).Stringer("to", to).Err(res.err).Msg("key circuit breaker state changed")
+		}
+	}
+}
+
+// outcomes 请求结果对 api 地址与密钥熔断器的影响
+func (r attemptResult) outcomes(canceled bool) (endpointOutcome outcome, keyOutcome outcome) {
+	switch {
+	case r.resp != nil:
+		return outcomeSuccess, outcomeSuccess
+	case canceled:
+		return outcomeIgnore, outcomeIgnore
+	case r.status == 0 || r.status >= http.StatusInternalServerError: // 网络错误、超时与 5xx 与密钥无关
+		return outcomeFailure, outcomeIgnore
+	case r.status == http.StatusUnauthorized || r.status == http.StatusForbidden || r.status == http.StatusTooManyRequests:
+		return outcomeSuccess, outcomeFailure
+	}
+	return outcomeSuccess, outcomeIgnore // 其他 4xx 为请求本身的问题
+}
+
+// Health api 地址与密钥的健康状态，与 ApiCfgList 的顺序一致 (构造后追加的 api 配置不统计)
+func (a AIClient) Health() []EndpointHealth {
+	now := time.Now()
+	health := make([]EndpointHealth, len(a.endpoints))
+	for i := range a.endpoints {
+		ep := &a.endpoints[i]
+		health[i] = EndpointHealth{Url: ep.url, HealthStats: ep.health.snapshot(a.breaker, now)}
+		for _, key := range ep.keys {
+			keyHealth := KeyHealth{Key: maskKey(key.auth), HealthStats: key.health.snapshot(a.breaker, now)}
+			keyHealth.InFlight, keyHealth.Latency = key.load.snapshot()
+			health[i].Keys = append(health[i].Keys, keyHealth)
+		}
+	}
+	return health
+}
+
+// maskKey 脱敏密钥，仅保留前 3 位与后 4 位
+func maskKey(auth string) string {
+	key := strings.TrimPrefix(auth, "Bearer ")
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:3] + "..." + key[len(key)-4:]
+}
@@ -0,0 +1,191 @@
+package ai_sdk
+
+import (
+	"errors"
+	"github.com/Clov614/go-ai-sdk/config"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	p := BreakerPolicy{FailureThreshold: 2, Cooldown: time.Minute, HalfOpenProbes: 1}
+	start := time.Now()
+	fail := errors.New("boom")
+	type step struct {
+		at        time.Duration // 相对 start 的时间
+		allow     *bool         // 不为 nil 时检查 allow 的结果
+		record    outcome
+		wantState BreakerState
+	}
+	yes, no := true, false
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "opens after threshold",
+			steps: []step{
+				{allow: &yes, record: outcomeFailure, wantState: BreakerClosed},
+				{allow: &yes, record: outcomeFailure, wantState: BreakerOpen},
+				{at: time.Second, allow: &no, wantState: BreakerOpen},
+			},
+		},
+		{
+			name: "success resets consecutive failures",
+			steps: []step{
+				{allow: &yes, record: outcomeFailure, wantState: BreakerClosed},
+				{allow: &yes, record: outcomeSuccess, wantState: BreakerClosed},
+				{allow: &yes, record: outcomeFailure, wantState: BreakerClosed},
+			},
+		},
+		{
+			name: "half-open probe success closes",
+			steps: []step{
+				{record: outcomeFailure},
+				{record: outcomeFailure, wantState: BreakerOpen},
+				{at: time.Minute, allow: &yes, wantState: BreakerHalfOpen},
+				{at: time.Minute, allow: &no, wantState: BreakerHalfOpen}, // 仅放行一个探测请求
+				{at: time.Minute, record: outcomeSuccess, wantState: BreakerClosed},
+				{at: time.Minute, allow: &yes, wantState: BreakerClosed},
+			},
+		},
+		{
+			name: "half-open probe failure reopens",
+			steps: []step{
+				{record: outcomeFailure},
+				{record: outcomeFailure, wantState: BreakerOpen},
+				{at: time.Minute, allow: &yes, wantState: BreakerHalfOpen},
+				{at: time.Minute, record: outcomeFailure, wantState: BreakerOpen},
+				{at: time.Minute + time.Second, allow: &no, wantState: BreakerOpen},
+			},
+		},
+		{
+			name: "ignored probe releases slot",
+			steps: []step{
+				{record: outcomeFailure},
+				{record: outcomeFailure, wantState: BreakerOpen},
+				{at: time.Minute, allow: &yes, wantState: BreakerHalfOpen},
+				{at: time.Minute, record: outcomeIgnore, wantState: BreakerHalfOpen},
+				{at: time.Minute, allow: &yes, wantState: BreakerHalfOpen},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b breaker
+			for i, s := range tt.steps {
+				now := start.Add(s.at)
+				if s.allow != nil {
+					if got := b.allow(p, now); got != *s.allow {
+						t.Fatalf("step %d allow() = %v, want %v", i, got, *s.allow)
+					}
+				}
+				if s.record != outcomeIgnore || s.allow == nil {
+					b.record(p, s.record, fail, now)
+				}
+				if got := b.snapshot(p, now).State; got != s.wantState {
+					t.Fatalf("step %d state = %s, want %s", i, got, s.wantState)
+				}
+			}
+		})
+	}
+	var disabled breaker
+	for i := 0; i < 5; i++ {
+		disabled.record(BreakerPolicy{}, outcomeFailure, fail, start)
+	}
+	if !disabled.allow(BreakerPolicy{}, start) {
+		t.Errorf("disabled breaker rejected request")
+	}
+}
+
+func TestAIClient_CircuitBreaker(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	var primaryCalls int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&primaryCalls, 1)
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(okBody))
+	}))
+	defer primary.Close()
+	backup, _ := newScriptedServer(t, nil)
+	a, err := NewClient([]config.APIConfig{
+		{Url: primary.URL, AuthList: []string{"sk-primary-0001"}},
+		{Url: backup.URL, AuthList: []string{"sk-backup-0002"}},
+	},
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+		WithBreakerPolicy(BreakerPolicy{FailureThreshold: 2, Cooldown: 50 * time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	send := func() {
+		t.Helper()
+		if _, err := a.Send(Request{Messages: []Message{{Role: userRole, Content: "hi"}}}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		send()
+	}
+	if got := atomic.LoadInt32(&primaryCalls); got != 2 {
+		t.Errorf("primary calls = %d, want 2 (skipped while open)", got)
+	}
+	health := a.Health()
+	if health[0].State != BreakerOpen || health[0].Failures != 2 || health[0].LastError == "" {
+		t.Errorf("primary health = %+v, want open with 2 failures", health[0].HealthStats)
+	}
+	if health[0].Keys[0].Key != "sk-...0001" || health[0].Keys[0].Failures != 0 {
+		t.Errorf("primary key health = %+v, want masked key without failures", health[0].Keys[0])
+	}
+	if health[1].State != BreakerClosed || health[1].Requests != 4 {
+		t.Errorf("backup health = %+v, want closed with 4 requests", health[1].HealthStats)
+	}
+
+	down.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	send() // 冷却结束后的探测请求
+	if got := atomic.LoadInt32(&primaryCalls); got != 3 {
+		t.Errorf("primary calls = %d, want 3 after cooldown", got)
+	}
+	if state := a.Health()[0].State; state != BreakerClosed {
+		t.Errorf("primary state = %s, want closed after successful probe", state)
+	}
+}
+
+func TestAIClient_KeyBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer sk-revoked-0001" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(okBody))
+	}))
+	defer server.Close()
+	a, err := NewClient([]config.APIConfig{{Url: server.URL, AuthList: []string{"sk-revoked-0001", "sk-valid-0002"}}},
+		WithBreakerPolicy(BreakerPolicy{FailureThreshold: 1, Cooldown: time.Minute}))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err = a.Send(Request{Messages: []Message{{Role: userRole, Content: "hi"}}}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	health := a.Health()[0]
+	if health.State != BreakerClosed {
+		t.Errorf("endpoint state = %s, want closed (401 is a key failure)", health.State)
+	}
+	if health.Keys[0].State != BreakerOpen || health.Keys[0].Requests != 1 {
+		t.Errorf("revoked key health = %+v, want open after 1 request", health.Keys[0].HealthStats)
+	}
+	if health.Keys[1].Requests != 3 {
+		t.Errorf("valid key requests = %d, want 3", health.Keys[1].Requests)
+	}
+}
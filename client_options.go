@@ -0,0 +1,250 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午4:00:00
+// @Desc AIClient 的选项式构造：超时、http.Client / Transport、请求头、组织与项目、User-Agent、请求节点、重试、熔断、负载均衡、限流与密钥隔离回调
+package ai_sdk
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/config"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	organizationHeader = "OpenAI-Organization"
+	projectHeader      = "OpenAI-Project"
+	userAgentHeader    = "User-Agent"
+)
+
+var clientOptionErr = errors.New("invalid client option") // 客户端选项不合法
+
+// ClientOption NewClient 的选项
+type ClientOption func(c *AIClient) error
+
+// NewClient 创建请求客户端，apiCfgList 为 api 地址与密钥列表，默认使用 config.DefaultModel、config.DefaultEndPoint 与 10s 超时
+// 选项或 api 配置不合法时返回错误，不会静默修正
+func NewClient(apiCfgList []config.APIConfig, opts ...ClientOption) (*AIClient, error) {
+	client := &AIClient{
+		ContentType:   config.DefaultContentType,
+		Model:         config.DefaultModel,
+		ApiCfgList:    apiCfgList,
+		EndPoint:      config.DefaultEndPoint,
+		timeout:       config.DefaultTimeout * time.Second,
+		header:        make(http.Header),
+		retry:         DefaultRetryPolicy(),
+		breaker:       DefaultBreakerPolicy(),
+		balancer:      FailoverBalancer(),
+		rateLimitWait: config.DefaultRateLimitWait * time.Second,
+	}
+	for _, opt := range opts {
+		if err := opt(client); err != nil {
+			return nil, fmt.Errorf("NewClient: %w", err)
+		}
+	}
+	if err := client.validate(); err != nil {
+		return nil, fmt.Errorf("NewClient: %w", err)
+	}
+	if client.client == nil {
+		client.client = &http.Client{}
+	} else {
+		c := *client.client // 不修改调用方的 http.Client
+		client.client = &c
+	}
+	client.client.Timeout = client.timeout
+	if err := client.buildEndpoints(); err != nil {
+		return nil, fmt.Errorf("NewClient: %w: %w", clientOptionErr, err)
+	}
+	return client, nil
+}
+
+// validate 校验客户端配置
+func (a *AIClient) validate() error {
+	if a.Model == "" {
+		return fmt.Errorf("%w: model is empty", clientOptionErr)
+	}
+	if len(a.ApiCfgList) == 0 {
+		return fmt.Errorf("%w: api config list is empty", clientOptionErr)
+	}
+	for i, apiCfg := range a.ApiCfgList {
+		u, err := url.Parse(apiCfg.Url + a.EndPoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: api config %d url %q is not an absolute http(s) url", clientOptionErr, i, apiCfg.Url+a.EndPoint)
+		}
+		if len(apiCfg.AuthList) == 0 {
+			return fmt.Errorf("%w: api config %d has no authorization", clientOptionErr, i)
+		}
+		for _, auth := range apiCfg.AuthList {
+			if strings.TrimSpace(strings.TrimPrefix(auth, "Bearer ")) == "" {
+				return fmt.Errorf("%w: api config %d has an empty authorization", clientOptionErr, i)
+			}
+		}
+		if err := validateRateLimits(apiCfg); err != nil {
+			return fmt.Errorf("%w: api config %d %w", clientOptionErr, i, err)
+		}
+	}
+	return nil
+}
+
+// WithModel 请求使用的模型
+func WithModel(model string) ClientOption {
+	return func(c *AIClient) error {
+		c.Model = model
+		return nil
+	}
+}
+
+// WithEndPoint 拼接在 api 地址之后的请求节点，如 "/v1/chat/completions"；api 地址已是完整地址时传入 ""
+func WithEndPoint(endPoint string) ClientOption {
+	return func(c *AIClient) error {
+		if endPoint != "" && !strings.HasPrefix(endPoint, "/") {
+			return fmt.Errorf("%w: endpoint %q must start with /", clientOptionErr, endPoint)
+		}
+		c.EndPoint = endPoint
+		return nil
+	}
+}
+
+// WithTimeout 单次请求超时时间 (流式请求为等待响应头的时间)，0 表示不限制
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *AIClient) error {
+		if timeout < 0 {
+			return fmt.Errorf("%w: negative timeout %s", clientOptionErr, timeout)
+		}
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// WithHTTPClient 使用调用方提供的 http.Client (复制后使用，超时以 WithTimeout 为准)
+// client 设置了 Transport 时所有 api 配置共用该 Transport，不再按 proxy_address 与 TLS 配置创建
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *AIClient) error {
+		if client == nil {
+			return fmt.Errorf("%w: nil http client", clientOptionErr)
+		}
+		c.client = client
+		c.customTransport = client.Transport != nil
+		return nil
+	}
+}
+
+// WithTransport 使用自定义 Transport，设置后所有 api 配置共用该 Transport，不再按 proxy_address 与 TLS 配置创建
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *AIClient) error {
+		if transport == nil {
+			return fmt.Errorf("%w: nil transport", clientOptionErr)
+		}
+		if c.client == nil {
+			c.client = &http.Client{}
+		} else {
+			client := *c.client
+			c.client = &client
+		}
+		c.client.Transport = transport
+		c.customTransport = true
+		return nil
+	}
+}
+
+// WithHeader 每个请求附带的额外请求头，Authorization 与 Content-Type 由客户端设置，不可覆盖
+func WithHeader(key string, value string) ClientOption {
+	return func(c *AIClient) error {
+		switch http.CanonicalHeaderKey(key) {
+		case "", "Authorization", "Content-Type":
+			return fmt.Errorf("%w: header %q cannot be set", clientOptionErr, key)
+		}
+		c.header.Add(key, value)
+		return nil
+	}
+}
+
+// WithOrganization 设置 OpenAI-Organization 请求头
+func WithOrganization(organization string) ClientOption {
+	return func(c *AIClient) error {
+		c.header.Set(organizationHeader, organization)
+		return nil
+	}
+}
+
+// WithProject 设置 OpenAI-Project 请求头
+func WithProject(project string) ClientOption {
+	return func(c *AIClient) error {
+		c.header.Set(projectHeader, project)
+		return nil
+	}
+}
+
+// WithUserAgent 设置 User-Agent 请求头
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *AIClient) error {
+		if userAgent == "" {
+			return fmt.Errorf("%w: empty user agent", clientOptionErr)
+		}
+		c.header.Set(userAgentHeader, userAgent)
+		return nil
+	}
+}
+
+// WithRetryPolicy 单个密钥的重试策略，MaxAttempts 为 1 时不重试
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *AIClient) error {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		c.retry = policy
+		return nil
+	}
+}
+
+// WithBreakerPolicy api 地址与密钥的熔断策略，FailureThreshold 小于 1 时不启用熔断
+func WithBreakerPolicy(policy BreakerPolicy) ClientOption {
+	return func(c *AIClient) error {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		c.breaker = policy
+		return nil
+	}
+}
+
+// WithBalancer api 配置与密钥的负载均衡策略，默认按配置顺序使用 (FailoverBalancer)
+func WithBalancer(balancer Balancer) ClientOption {
+	return func(c *AIClient) error {
+		if balancer == nil {
+			return fmt.Errorf("%w: nil balancer", clientOptionErr)
+		}
+		c.balancer = balancer
+		return nil
+	}
+}
+
+// WithRateLimitWait 所有密钥均达到客户端限流 (api 配置的 rate_limit / key_limits) 时的最长等待时间，0 表示不等待
+func WithRateLimitWait(wait time.Duration) ClientOption {
+	return func(c *AIClient) error {
+		if wait < 0 {
+			return fmt.Errorf("%w: negative rate limit wait %s", clientOptionErr, wait)
+		}
+		c.rateLimitWait = wait
+		return nil
+	}
+}
+
+// WithKeyQuarantineHandler 密钥因 401、insufficient_quota、account_deactivated 被隔离时的回调
+func WithKeyQuarantineHandler(fn KeyQuarantineFunc) ClientOption {
+	return func(c *AIClient) error {
+		c.onQuarantine = fn
+		return nil
+	}
+}
+
+// WithClientParams 客户端默认生成参数 (优先级最低)
+func WithClientParams(params config.ChatParams) ClientOption {
+	return func(c *AIClient) error {
+		c.Params = params
+		return nil
+	}
+}
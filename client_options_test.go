@@ -0,0 +1,152 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午4:20:00
+// @Desc AIClient 选项式构造测试
+package ai_sdk
+
+import (
+	"errors"
+	"github.com/Clov614/go-ai-sdk/config"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingTransport struct {
+	calls int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewAIClient_timeout(t *testing.T) {
+	tests := []struct {
+		timeout int
+		want    time.Duration
+	}{
+		{timeout: 0, want: 10 * time.Second},
+		{timeout: 10, want: 10 * time.Second},
+		{timeout: 30, want: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		a := NewAIClient([]config.APIConfig{{Url: "https://api.openai.com", AuthList: []string{"sk"}}}, config.DefaultModel, config.DefaultEndPoint, tt.timeout)
+		if got := a.requestTimeout(); got != tt.want {
+			t.Errorf("NewAIClient(timeout=%d) timeout = %s, want %s", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	withKey := func(modify func(cfg *config.AICfg)) config.AICfg {
+		cfg := config.DefaultConfig()
+		cfg.ApiCfgs[0].AuthList = []string{"sk-1"}
+		modify(&cfg)
+		return cfg
+	}
+	tests := []struct {
+		name    string
+		cfg     config.AICfg
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default timeout", cfg: withKey(func(cfg *config.AICfg) { cfg.Timeout = 0 }), want: config.DefaultTimeout * time.Second},
+		{name: "short timeout kept", cfg: withKey(func(cfg *config.AICfg) { cfg.Timeout = 3 }), want: 3 * time.Second},
+		{name: "long timeout", cfg: withKey(func(cfg *config.AICfg) { cfg.Timeout = 60 }), want: 60 * time.Second},
+		{name: "negative timeout", cfg: withKey(func(cfg *config.AICfg) { cfg.Timeout = -1 }), wantErr: true},
+		{name: "unknown balancer", cfg: withKey(func(cfg *config.AICfg) { cfg.Balancer = "unknown" }), wantErr: true},
+		{name: "no authorization", cfg: config.DefaultConfig(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newClientFromConfig(tt.cfg)
+			if tt.wantErr {
+				if !errors.Is(err, clientOptionErr) {
+					t.Errorf("newClientFromConfig() error = %v, want clientOptionErr", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newClientFromConfig() error = %v", err)
+			}
+			if got := client.requestTimeout(); got != tt.want {
+				t.Errorf("newClientFromConfig() timeout = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewClient_validate(t *testing.T) {
+	valid := []config.APIConfig{{Url: "https://api.openai.com", AuthList: []string{"sk-1"}}}
+	tests := []struct {
+		name string
+		cfgs []config.APIConfig
+		opts []ClientOption
+	}{
+		{name: "no api config"},
+		{name: "relative url", cfgs: []config.APIConfig{{Url: "api.openai.com", AuthList: []string{"sk-1"}}}},
+		{name: "no authorization", cfgs: []config.APIConfig{{Url: "https://api.openai.com"}}},
+		{name: "empty authorization", cfgs: []config.APIConfig{{Url: "https://api.openai.com", AuthList: []string{"Bearer "}}}},
+		{name: "empty model", cfgs: valid, opts: []ClientOption{WithModel("")}},
+		{name: "endpoint without slash", cfgs: valid, opts: []ClientOption{WithEndPoint("v1/chat/completions")}},
+		{name: "negative timeout", cfgs: valid, opts: []ClientOption{WithTimeout(-time.Second)}},
+		{name: "nil transport", cfgs: valid, opts: []ClientOption{WithTransport(nil)}},
+		{name: "nil http client", cfgs: valid, opts: []ClientOption{WithHTTPClient(nil)}},
+		{name: "authorization header", cfgs: valid, opts: []ClientOption{WithHeader("authorization", "x")}},
+		{name: "empty user agent", cfgs: valid, opts: []ClientOption{WithUserAgent("")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClient(tt.cfgs, tt.opts...); !errors.Is(err, clientOptionErr) {
+				t.Errorf("NewClient() error = %v, want clientOptionErr", err)
+			}
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	var gotHeader http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-test","choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+	transport := &countingTransport{}
+	callerClient := &http.Client{Timeout: time.Hour}
+	a, err := NewClient([]config.APIConfig{{Url: server.URL, AuthList: []string{"sk-test"}}},
+		WithHTTPClient(callerClient),
+		WithTransport(transport),
+		WithTimeout(3*time.Second),
+		WithModel("gpt-4.1"),
+		WithOrganization("org-1"),
+		WithProject("proj-1"),
+		WithUserAgent("my-bot/1.0"),
+		WithHeader("X-Trace", "abc"),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err = a.Send(Request{Messages: []Message{{Role: userRole, Content: "hi"}}}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	for key, want := range map[string]string{
+		"Openai-Organization": "org-1",
+		"Openai-Project":      "proj-1",
+		"User-Agent":          "my-bot/1.0",
+		"X-Trace":             "abc",
+		"Authorization":       "Bearer sk-test",
+	} {
+		if got := gotHeader.Get(key); got != want {
+			t.Errorf("header %s = %q, want %q", key, got, want)
+		}
+	}
+	if atomic.LoadInt32(&transport.calls) != 1 {
+		t.Errorf("custom transport calls = %d, want 1", transport.calls)
+	}
+	if a.requestTimeout() != 3*time.Second || callerClient.Timeout != time.Hour || callerClient.Transport != nil {
+		t.Errorf("timeout = %s, caller client modified: %+v", a.requestTimeout(), callerClient)
+	}
+}
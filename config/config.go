@@ -6,9 +6,10 @@ package config
 
 import (
 	"errors"
-	"github.com/Clov614/go-ai-sdk/logging"
+	"fmt"
 	"github.com/Clov614/go-ai-sdk/utils/configutil"
 	"os"
+	"path/filepath"
 )
 
 type AICfg struct {
@@ -17,33 +18,208 @@ type AICfg struct {
 	EndPoint    string      `yaml:"end_point" comment:"请求节点 默认: /v1/chat/completions"`
 	ApiCfgs     []APIConfig `yaml:"configs" comment:"API 配置列表"`
 	// 功能设置项
-	Timeout        int `yaml:"timeout" comment:"请求超时时间，单位秒，默认 10s"`
-	HistoryNum     int `yaml:"history_num,omitempty" comment:"最大上下文长度 默认: 10"`
-	SessionTimeOut int `yaml:"session_time_out" comment:"对话会话超时时间 单位: 分钟 默认: 2 minute"`
+	Timeout         int    `yaml:"timeout" comment:"请求超时时间，单位秒，默认 10s"`
+	HistoryNum      int    `yaml:"history_num,omitempty" comment:"最多保留的问答轮数 默认: 10，同时受模型上下文窗口的 token 预算约束"`
+	ContextWindow   int    `yaml:"context_window,omitempty" comment:"模型上下文窗口大小 (token) 默认按模型名推断，未知模型为 8192"`
+	TokenizerDir    string `yaml:"tokenizer_dir,omitempty" comment:"tiktoken 词表目录 (cl100k_base.tiktoken / o200k_base.tiktoken) 默认不加载 (ai_sdk.Init 使用 ./cfg/tiktoken)，词表不存在时按字符估算 token 数"`
+	SessionTimeOut  int    `yaml:"session_time_out" comment:"对话会话超时时间 单位: 分钟 默认: 2 minute"`
+	MaxSessions     int    `yaml:"max_sessions,omitempty" comment:"内存中最多保留的会话数，超出时移除最久未使用的会话 默认: 0 不限制"`
+	MaxSessionBytes int    `yaml:"max_session_bytes,omitempty" comment:"单个会话问答记录的最大字节数，超出时移除最早的问答 默认: 0 不限制"`
+	MaxToolRounds   int    `yaml:"max_tool_rounds,omitempty" comment:"单次对话最多执行的工具调用轮数 默认: 5"`
+	ToolLoopTimeout int    `yaml:"tool_loop_timeout,omitempty" comment:"单次对话工具调用的最长耗时，单位秒，超出后不再发起新一轮工具调用 默认: 0 不限制"`
+	ToolWorkers     int    `yaml:"tool_workers,omitempty" comment:"同一轮中并发执行的工具调用数 默认: 4"`
+	ToolTimeout     int    `yaml:"tool_timeout,omitempty" comment:"单个工具调用的超时时间，单位秒 默认: 0 不限制"`
+	// 重试、负载均衡与熔断
+	Retry         RetryCfg   `yaml:"retry,omitempty" comment:"单个密钥的重试策略，429、5xx、连接重置与超时在切换下一个密钥前按退避时间重试"`
+	Balancer      string     `yaml:"balancer,omitempty" comment:"api 配置与密钥的负载均衡策略: failover (按顺序使用，失败时切换) / round_robin / weighted / least_in_flight / latency / random 默认: failover"`
+	Breaker       BreakerCfg `yaml:"circuit_breaker,omitempty" comment:"api 地址与密钥的熔断策略，连续失败后在冷却时间内跳过该 api 地址或密钥"`
+	RateLimitWait int        `yaml:"rate_limit_wait,omitempty" comment:"所有密钥均达到客户端限流 (rate_limit / key_limits) 时的最长等待时间，单位秒 默认: 30，小于 0 表示不等待"`
+	// 生成参数
+	Params ChatParams `yaml:"params,omitempty" comment:"默认生成参数 (可选)，会被会话与单次请求中设置的同名参数覆盖"`
 }
 
 type APIConfig struct {
 	Url       string   `yaml:"api_url" comment:"api地址 默认: https://api.openai.com/v1/chat/completions"`
 	AuthList  []string `yaml:"authorization_list" comment:"OPEN-API-KEY api密钥列表 (必填)"`
-	ProxyAddr string   `yaml:"proxy_address,omitempty" comment:"代理地址 (可选)"`
+	ProxyAddr string   `yaml:"proxy_address,omitempty" comment:"代理地址 (可选) 支持 http://、https://、socks5://，未写协议时按 http 代理处理"`
+	// TLS 设置
+	CACertFile         string `yaml:"ca_cert_file,omitempty" comment:"额外信任的 CA 证书 (PEM) 路径 (可选)，用于自签名证书的网关"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty" comment:"跳过 TLS 证书校验 (可选) 仅用于本地网关调试"`
+	// 负载均衡
+	Weight int `yaml:"weight,omitempty" comment:"权重 (可选) balancer 为 weighted 时按权重分配请求，同一配置的密钥平分权重 默认: 1"`
+	// 客户端限流
+	RateLimit RateLimitCfg `yaml:"rate_limit,omitempty" comment:"每个密钥的客户端限流 (可选)"`
+	KeyLimits []KeyLimit   `yaml:"key_limits,omitempty" comment:"单个密钥的客户端限流 (可选)，覆盖 rate_limit"`
 }
 
+// RateLimitCfg 密钥的客户端限流，0 表示不限制
+type RateLimitCfg struct {
+	RPM            int `yaml:"rpm,omitempty" comment:"每分钟请求数上限"`
+	TPM            int `yaml:"tpm,omitempty" comment:"每分钟 token 数上限，请求前按提示词 token 数 + 最大生成 token 数预估，响应后按实际用量修正"`
+	MaxConcurrency int `yaml:"max_concurrency,omitempty" comment:"同时进行的请求数上限"`
+}
+
+// KeyLimit 单个密钥的客户端限流
+type KeyLimit struct {
+	Auth  string       `yaml:"authorization" comment:"密钥，需在 authorization_list 中"`
+	Limit RateLimitCfg `yaml:"limit" comment:"该密钥的限流设置"`
+}
+
+// RetryCfg 单个密钥的重试策略
+type RetryCfg struct {
+	MaxAttempts int `yaml:"max_attempts,omitempty" comment:"单个密钥最多请求次数 (含首次) 默认: 3，1 表示不重试"`
+	BaseDelay   int `yaml:"base_delay_ms,omitempty" comment:"首次重试的退避时间，单位毫秒，之后每次翻倍并加入随机抖动 默认: 500"`
+	MaxDelay    int `yaml:"max_delay_ms,omitempty" comment:"单次等待的最长时间，单位毫秒，Retry-After 超过该值时直接切换下一个密钥 默认: 20000"`
+}
+
+// BreakerCfg api 地址与密钥的熔断策略
+type BreakerCfg struct {
+	FailureThreshold int `yaml:"failure_threshold,omitempty" comment:"连续失败次数达到该值时熔断 默认: 5，小于 0 表示不启用"`
+	Cooldown         int `yaml:"cooldown,omitempty" comment:"熔断持续时间，单位秒，结束后放行探测请求，成功则恢复 默认: 30"`
+	HalfOpenProbes   int `yaml:"half_open_probes,omitempty" comment:"冷却结束后同时放行的探测请求数 默认: 1"`
+}
+
+// ChatParams 对话生成参数，均为可选，未设置 (nil/空) 的参数不会发送
+type ChatParams struct {
+	Temperature         *float64        `json:"temperature,omitempty" yaml:"temperature,omitempty" comment:"采样温度 0~2"`
+	TopP                *float64        `json:"top_p,omitempty" yaml:"top_p,omitempty" comment:"核采样概率 0~1"`
+	MaxTokens           *int            `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty" comment:"最大生成 token 数"`
+	MaxCompletionTokens *int            `json:"max_completion_tokens,omitempty" yaml:"max_completion_tokens,omitempty" comment:"最大生成 token 数 (含推理 token, o 系列模型使用)"`
+	Stop                []string        `json:"stop,omitempty" yaml:"stop,omitempty" comment:"停止词 最多 4 个"`
+	PresencePenalty     *float64        `json:"presence_penalty,omitempty" yaml:"presence_penalty,omitempty" comment:"存在惩罚 -2~2"`
+	FrequencyPenalty    *float64        `json:"frequency_penalty,omitempty" yaml:"frequency_penalty,omitempty" comment:"频率惩罚 -2~2"`
+	Seed                *int            `json:"seed,omitempty" yaml:"seed,omitempty" comment:"随机种子"`
+	N                   *int            `json:"n,omitempty" yaml:"n,omitempty" comment:"生成回答数量"`
+	LogitBias           map[string]int  `json:"logit_bias,omitempty" yaml:"logit_bias,omitempty" comment:"token 偏置 token_id: -100~100"`
+	User                string          `json:"user,omitempty" yaml:"user,omitempty" comment:"终端用户标识"`
+	Logprobs            *bool           `json:"logprobs,omitempty" yaml:"logprobs,omitempty" comment:"是否返回 token 对数概率"`
+	TopLogprobs         *int            `json:"top_logprobs,omitempty" yaml:"top_logprobs,omitempty" comment:"每个位置返回的候选 token 数 0~20 (需开启 logprobs)"`
+	ParallelToolCalls   *bool           `json:"parallel_tool_calls,omitempty" yaml:"parallel_tool_calls,omitempty" comment:"是否允许并行调用工具"`
+	ResponseFormat      *ResponseFormat `json:"response_format,omitempty" yaml:"response_format,omitempty" comment:"输出格式"`
+}
+
+// ResponseFormat 输出格式
+type ResponseFormat struct {
+	Type       string            `json:"type" yaml:"type" comment:"text / json_object / json_schema"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty" yaml:"json_schema,omitempty" comment:"type 为 json_schema 时必填"`
+}
+
+// JSONSchemaFormat 结构化输出的 JSON Schema 描述
+type JSONSchemaFormat struct {
+	Name        string      `json:"name" yaml:"name" comment:"schema 名称"`
+	Description string      `json:"description,omitempty" yaml:"description,omitempty" comment:"schema 描述"`
+	Schema      interface{} `json:"schema,omitempty" yaml:"schema,omitempty" comment:"JSON Schema"`
+	Strict      bool        `json:"strict,omitempty" yaml:"strict,omitempty" comment:"是否严格遵循 schema"`
+}
+
+// Merge 以 override 中已设置的参数覆盖 p，返回合并后的新参数
+func (p ChatParams) Merge(override ChatParams) ChatParams {
+	if override.Temperature != nil {
+		p.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		p.TopP = override.TopP
+	}
+	if override.MaxTokens != nil {
+		p.MaxTokens = override.MaxTokens
+	}
+	if override.MaxCompletionTokens != nil {
+		p.MaxCompletionTokens = override.MaxCompletionTokens
+	}
+	if override.Stop != nil {
+		p.Stop = override.Stop
+	}
+	if override.PresencePenalty != nil {
+		p.PresencePenalty = override.PresencePenalty
+	}
+	if override.FrequencyPenalty != nil {
+		p.FrequencyPenalty = override.FrequencyPenalty
+	}
+	if override.Seed != nil {
+		p.Seed = override.Seed
+	}
+	if override.N != nil {
+		p.N = override.N
+	}
+	if override.LogitBias != nil {
+		p.LogitBias = override.LogitBias
+	}
+	if override.User != "" {
+		p.User = override.User
+	}
+	if override.Logprobs != nil {
+		p.Logprobs = override.Logprobs
+	}
+	if override.TopLogprobs != nil {
+		p.TopLogprobs = override.TopLogprobs
+	}
+	if override.ParallelToolCalls != nil {
+		p.ParallelToolCalls = override.ParallelToolCalls
+	}
+	if override.ResponseFormat != nil {
+		p.ResponseFormat = override.ResponseFormat
+	}
+	return p
+}
+
+const (
+	DefaultContentType      = "application/json"
+	DefaultModel            = "gpt-4o-mini"
+	DefaultUrl              = "https://api.openai.com/v1/chat/completions"
+	DefaultHistoryNum       = 10 // 默认上下文长度
+	DefaultEndPoint         = "/v1/chat/completions"
+	DefaultTimeout          = 10
+	DefaultSessionTimeout   = 2
+	DefaultMaxToolRounds    = 5     // 默认工具调用轮数
+	DefaultToolWorkers      = 4     // 默认工具并发数
+	DefaultRetryAttempts    = 3     // 默认单个密钥请求次数
+	DefaultRetryBaseDelay   = 500   // 默认首次重试退避时间 (毫秒)
+	DefaultRetryMaxDelay    = 20000 // 默认单次最长等待时间 (毫秒)
+	DefaultBreakerThreshold = 5     // 默认熔断的连续失败次数
+	DefaultBreakerCooldown  = 30    // 默认熔断持续时间 (秒)
+	DefaultRateLimitWait    = 30    // 默认客户端限流的最长等待时间 (秒)
+	defaultAuthExample      = "sk-xxxxxxx"
+	defaultProxyAddr        = "127.0.0.1:7890"
+)
+
+// Config 全局配置，默认为 DefaultConfig()，通过 Setup 或 ai_sdk.New 设置，不会在导入时读取配置文件
+var Config = DefaultConfig()
+
 const (
-	DefaultContentType    = "application/json"
-	DefaultModel          = "gpt-4o-mini"
-	DefaultUrl            = "https://api.openai.com/v1/chat/completions"
-	DefaultHistoryNum     = 10 // 默认上下文长度
-	DefaultEndPoint       = "/v1/chat/completions"
-	DefaultTimeout        = 10
-	DefaultSessionTimeout = 2
-	defaultAuthExample    = "sk-xxxxxxx"
-	defaultProxyAddr      = "127.0.0.1:7890"
+	DefaultPath     = "./cfg/"      // 默认配置目录
+	DefaultFileName = "ai-cfg.yaml" // 默认配置文件名
 )
 
-var Config = AICfg{
-	ContentType: DefaultContentType,
-	Model:       DefaultModel,
-	ApiCfgs: []APIConfig{
+// DefaultConfig 默认配置 (不含 API 密钥)
+func DefaultConfig() AICfg {
+	return AICfg{
+		ContentType:    DefaultContentType,
+		Model:          DefaultModel,
+		ApiCfgs:        []APIConfig{{Url: DefaultUrl}},
+		Timeout:        DefaultTimeout,
+		HistoryNum:     DefaultHistoryNum,
+		EndPoint:       DefaultEndPoint,
+		SessionTimeOut: DefaultSessionTimeout,
+		MaxToolRounds:  DefaultMaxToolRounds,
+		ToolWorkers:    DefaultToolWorkers,
+		Retry: RetryCfg{
+			MaxAttempts: DefaultRetryAttempts,
+			BaseDelay:   DefaultRetryBaseDelay,
+			MaxDelay:    DefaultRetryMaxDelay,
+		},
+		Breaker: BreakerCfg{
+			FailureThreshold: DefaultBreakerThreshold,
+			Cooldown:         DefaultBreakerCooldown,
+			HalfOpenProbes:   1,
+		},
+		RateLimitWait: DefaultRateLimitWait,
+	}
+}
+
+// exampleConfig 生成配置文件模板时使用的示例配置
+func exampleConfig() AICfg {
+	cfg := DefaultConfig()
+	cfg.ApiCfgs = []APIConfig{
 		{
 			Url: DefaultUrl,
 			AuthList: []string{
@@ -52,28 +228,32 @@ var Config = AICfg{
 			},
 			ProxyAddr: defaultProxyAddr,
 		},
-	},
-	Timeout:        DefaultTimeout,
-	HistoryNum:     DefaultHistoryNum,
-	EndPoint:       DefaultEndPoint,
-	SessionTimeOut: DefaultSessionTimeout,
+	}
+	return cfg
 }
 
-var defaultPath = "./cfg/"
-
-var defaultSaveFileName = "ai-cfg.yaml"
+// LoadFile 读取 yaml 配置文件，未设置的配置项使用默认值，不会写入文件
+func LoadFile(path string) (AICfg, error) {
+	cfg := DefaultConfig()
+	if err := configutil.Load(&cfg, filepath.Dir(path), filepath.Base(path)); err != nil {
+		return cfg, fmt.Errorf("config.LoadFile %s: %w", path, err)
+	}
+	return cfg, nil
+}
 
-func init() {
-	err := configutil.Load(&Config, defaultPath, defaultSaveFileName)
+// Setup 兼容旧版本的初始化方式：读取 dir 目录下的 ai-cfg.yaml 到 Config，文件不存在时生成配置模板
+// 读取后会重新写入配置文件以补全新增的配置项
+func Setup(dir string) error {
+	cfg, err := LoadFile(filepath.Join(dir, DefaultFileName))
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			err = configutil.Save(&Config, defaultPath, defaultSaveFileName)
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
 		}
-		logging.ErrorWithErr(err, "error load config")
+		cfg = exampleConfig()
 	}
-	//cfg.verifiability() // 校验设置项是否合规
-	err = configutil.Save(&Config, defaultPath, defaultSaveFileName)
-	if err != nil {
-		logging.ErrorWithErr(err, "error saving config")
+	Config = cfg
+	if err = configutil.Save(&Config, dir, DefaultFileName); err != nil {
+		return fmt.Errorf("config.Setup save: %w", err)
 	}
+	return nil
 }
@@ -8,10 +8,21 @@ import "errors"
 
 // 错误定义
 var (
-	networkErr          = errors.New("network error")          // 网络连接错误
-	methodNotAllowedErr = errors.New("405 Method Not Allowed") // 请求方法错误
-	unAuthErr           = errors.New("401 Unauthorized")       // 鉴权失败错误
-	configErr           = errors.New("ai-cfg.yaml error ")     // 本地配置文件错误
+	networkErr          = errors.New("network error")                    // 网络连接错误
+	methodNotAllowedErr = errors.New("405 Method Not Allowed")           // 请求方法错误
+	unAuthErr           = errors.New("401 Unauthorized")                 // 鉴权失败错误
+	configErr           = errors.New("ai-cfg.yaml error ")               // 本地配置文件错误
+	funcNotFoundErr     = errors.New("function not registered")          // 模型请求了未注册的方法
+	emptyChoicesErr     = errors.New("response choices empty")           // 响应中没有任何 choice
+	funcRegisterErr     = errors.New("function register failed")         // 方法定义不合法，拒绝注册
+	sessionClosedErr    = errors.New("session closed")                   // 会话主体已关闭
+	toolNotAllowedErr   = errors.New("tool not allowed in this session") // 模型请求了会话不允许使用的工具
+	rateLimitErr        = errors.New("429 Too Many Requests")            // 触发接口限流
+	serverErr           = errors.New("upstream server error")            // 接口返回 5xx
+	circuitOpenErr      = errors.New("circuit breaker open")             // api 地址或密钥熔断中
+	localRateLimitErr   = errors.New("client-side rate limit reached")   // 所有密钥均达到客户端限流
+	quotaExceededErr    = errors.New("insufficient quota")               // 密钥额度已用尽
+	keyQuarantinedErr   = errors.New("all api keys quarantined")         // 所有可用密钥均已被隔离
 )
 
 func (r Ret) Error() string {
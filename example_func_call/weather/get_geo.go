@@ -5,6 +5,7 @@
 package weather
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,7 +19,7 @@ var (
 )
 
 // getCityCodeByAddr 根据地址获取城市代码 cityAddress: 城市地址，如: 泉州市永春县 isMultiDay: 是否获取多日天气
-func (w *Weather) getCityCodeByAddr(cityAddress string) (cityCode string, err error) {
+func (w *Weather) getCityCodeByAddr(ctx context.Context, cityAddress string) (cityCode string, err error) {
 	// 构建请求参数
 	params := url.Values{}
 	params.Add("key", w.key)
@@ -26,7 +27,7 @@ func (w *Weather) getCityCodeByAddr(cityAddress string) (cityCode string, err er
 
 	// 拼接完整 URL
 	reqURL := fmt.Sprintf("%s?%s", geoReqUrl, params.Encode())
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		return "", fmt.Errorf("NewWeather NewRequest err: %w", err)
 	}
@@ -5,6 +5,7 @@
 package weather
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -35,6 +36,12 @@ type DefaultWeatherResp struct {
 	Err error
 }
 
+// Result AI调用获取天气的结果
+type Result struct {
+	Lives     []Live     `json:"lives,omitempty"`     // 实时天气
+	Forecasts []Forecast `json:"forecasts,omitempty"` // 多日天气预报
+}
+
 func NewWeather(apikey string) *Weather {
 	return &Weather{
 		client: &http.Client{
@@ -46,30 +53,50 @@ func NewWeather(apikey string) *Weather {
 
 // Call AI调用获取天气
 func (w *Weather) Call(params string) (jsonStr string, err error) {
-	// 注册调用函数以及触发规则
-	type weatherProperties struct {
-		CityAddr string `json:"city_addr"` // 城市地址
-		IsMulti  bool   `json:"is_multi"`
-	}
-	var properties weatherProperties
+	return w.CallWithContext(context.Background(), params)
+}
+
+// Args AI调用获取天气的参数，同时用于生成工具的参数 schema (ai_sdk.NewFunction[weather.Args])
+type Args struct {
+	CityAddr string `json:"city_addr" jsonschema:"description=地址，如：国家，城市，县、区地址"` // 城市地址
+	IsMulti  bool   `json:"is_multi" jsonschema:"description=是否获取多日天气"`
+}
+
+// CallWithContext AI调用获取天气，会话取消时中断天气请求
+func (w *Weather) CallWithContext(ctx context.Context, params string) (jsonStr string, err error) {
+	var properties Args
 	err = json.Unmarshal([]byte(params), &properties)
 	if err != nil {
 		return "", fmt.Errorf("call_err: json.Unmarshal([]byte(params)) %w: %w", GetWeatherErr, err)
 	}
-	resp := w.GetWeatherByCityAddr(properties.CityAddr, properties.IsMulti)
-	if resp.Err != nil {
-		return "", fmt.Errorf("call_err: GetWeatherByCityAddr() error: %w", resp.Err)
+	result, err := w.GetWeather(ctx, properties)
+	if err != nil {
+		return "", fmt.Errorf("call_err: %w", err)
 	}
-	bytes, err := json.Marshal(resp) // nolint
+	bytes, err := json.Marshal(result)
 	if err != nil {
 		return "", fmt.Errorf("call_err: %w json.Marshal() error: %w", GetWeatherErr, err)
 	}
 	return string(bytes), nil
 }
 
+// GetWeather 类型化的获取天气方法，可直接通过 ai_sdk.RegisterTyped 注册
+func (w *Weather) GetWeather(ctx context.Context, args Args) (Result, error) {
+	resp := w.GetWeatherByCityAddrWithContext(ctx, args.CityAddr, args.IsMulti)
+	if resp.Err != nil {
+		return Result{}, fmt.Errorf("GetWeatherByCityAddr() error: %w", resp.Err)
+	}
+	return Result{Lives: resp.Lives, Forecasts: resp.Forecasts}, nil
+}
+
 // GetWeatherByCityAddr 根据国家，城市，县、区地址获取天气 isMultiDay: 是否获取多日天气
 func (w *Weather) GetWeatherByCityAddr(cityAddr string, isMultiDay bool) (weatherResp DefaultWeatherResp) {
-	cityCode, err := w.getCityCodeByAddr(cityAddr)
+	return w.GetWeatherByCityAddrWithContext(context.Background(), cityAddr, isMultiDay)
+}
+
+// GetWeatherByCityAddrWithContext 携带 context 获取天气
+func (w *Weather) GetWeatherByCityAddrWithContext(ctx context.Context, cityAddr string, isMultiDay bool) (weatherResp DefaultWeatherResp) {
+	cityCode, err := w.getCityCodeByAddr(ctx, cityAddr)
 	if err != nil {
 		weatherResp.Err = err
 		return
@@ -86,7 +113,7 @@ func (w *Weather) GetWeatherByCityAddr(cityAddr string, isMultiDay bool) (weathe
 	}
 	// 拼接完整 URL
 	reqURL := fmt.Sprintf("%s?%s", weatherReqUrl, params.Encode())
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		weatherResp.Err = fmt.Errorf("NewWeather NewRequest err: %w", err)
 		return
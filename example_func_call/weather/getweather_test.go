@@ -64,7 +64,9 @@ func TestRegisterWeatherFunc(t *testing.T) {
 		//CustomTrigger: nil, // 暂时不测试
 	}
 
-	ai_sdk.FuncRegister.Register(&funcCallInfo, []string{"天气", "weather"})
+	if err := ai_sdk.FuncRegister.Register(&funcCallInfo, []string{"天气", "weather"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
 
 	tools := ai_sdk.FuncRegister.GetToolsByContent("永春最近天气怎么样")
 	if *tools == nil || len(*tools) == 0 {
@@ -8,6 +8,9 @@ const (
 	ObjType    = "object"
 	StringType = "string"
 	IntType    = "integer"
-	FloatType  = "float"
+	FloatType  = NumberType // Deprecated: JSON Schema 中没有 float 类型，请使用 NumberType
 	BoolType   = "boolean"
+	NumberType = "number"
+	ArrayType  = "array"
+	NullType   = "null"
 )
@@ -0,0 +1,131 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午2:40:00
+// @Desc 结构化输出：按 Go 类型生成 json_schema 约束模型输出并解析
+package ai_sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/config"
+	"github.com/Clov614/go-ai-sdk/global"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	responseFormatJSONSchema = "json_schema"
+	defaultSchemaName        = "response"
+	maxSchemaNameLen         = 64
+	jsonOutputMaxRetry       = 2 // 输出无法解析时携带错误信息重试的次数
+)
+
+var (
+	refusalErr       = errors.New("model refused to answer")  // 模型拒绝按 schema 输出
+	jsonOutputErr    = errors.New("json output parse failed") // 重试后仍无法解析
+	schemaNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+)
+
+// SendJSON 以 T 的 JSON Schema 作为 response_format (strict) 发起请求，并将第一个 choice 解析为 T
+// 输出无法解析或不符合 schema 时，会将错误信息反馈给模型重试
+func SendJSON[T any](a AIClient, req Request) (result T, err error) {
+	return SendJSONWithContext[T](context.Background(), a, req)
+}
+
+// SendJSONWithContext 携带 context 发起结构化输出请求
+func SendJSONWithContext[T any](ctx context.Context, a AIClient, req Request) (result T, err error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	schema, err := schemaOfType(t, true)
+	if err != nil {
+		return result, fmt.Errorf("SendJSON schemaOfType(%s): %w", t, err)
+	}
+	if schema.Type != global.ObjType { // 结构化输出要求根节点为 object
+		return result, fmt.Errorf("SendJSON %s: %w: root must be a struct", t, schemaUnsupportedErr)
+	}
+	req.ResponseFormat = &config.ResponseFormat{
+		Type: responseFormatJSONSchema,
+		JSONSchema: &config.JSONSchemaFormat{
+			Name:   schemaName(t),
+			Schema: schema,
+			Strict: true,
+		},
+	}
+	msgs := append([]Message(nil), req.Messages...)
+	for attempt := 0; ; attempt++ {
+		req.Messages = msgs
+		resp, err := a.SendWithContext(ctx, req)
+		if err != nil {
+			return result, fmt.Errorf("SendJSON send err: %w", err)
+		}
+		choices := resp.GetData().Choices
+		if len(choices) == 0 {
+			return result, fmt.Errorf("SendJSON: %w", emptyChoicesErr)
+		}
+		message := choices[0].Message
+		if message.Refusal != "" {
+			return result, fmt.Errorf("%w: %s", refusalErr, message.Refusal)
+		}
+		content := extractJSON(message.Content)
+		parseErr := validateJSON(schema, []byte(content))
+		if parseErr == nil {
+			var v T
+			if parseErr = json.Unmarshal([]byte(content), &v); parseErr == nil {
+				return v, nil
+			}
+		}
+		if attempt >= jsonOutputMaxRetry {
+			return result, fmt.Errorf("%w after %d attempts: %w", jsonOutputErr, attempt+1, parseErr)
+		}
+		// 将错误反馈给模型重试
+		msgs = append(msgs,
+			Message{Role: assistantRole, Content: message.Content},
+			Message{Role: userRole, Content: fmt.Sprintf("上一次的输出不是符合 schema 的 JSON (%v)，请只输出符合 schema 的 JSON，不要包含其它内容。", parseErr)},
+		)
+	}
+}
+
+// extractJSON 去除模型输出中包裹 JSON 的代码块与前后说明文字
+func extractJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimPrefix(content, "json")
+		if end := strings.LastIndex(content, "```"); end != -1 {
+			content = content[:end]
+		}
+		content = strings.TrimSpace(content)
+	}
+	if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
+		return content
+	}
+	start := strings.IndexAny(content, "{[")
+	if start == -1 {
+		return content
+	}
+	closing := "}"
+	if content[start] == '[' {
+		closing = "]"
+	}
+	if end := strings.LastIndex(content, closing); end > start {
+		return content[start : end+1]
+	}
+	return content
+}
+
+// schemaName 由类型名生成 json_schema.name (仅允许 a-zA-Z0-9_-，最长 64)
+func schemaName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	name := schemaNameRegexp.ReplaceAllString(t.Name(), "_")
+	if name == "" {
+		return defaultSchemaName
+	}
+	if len(name) > maxSchemaNameLen {
+		name = name[:maxSchemaNameLen]
+	}
+	return name
+}
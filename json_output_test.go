@@ -0,0 +1,80 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午3:20:00
+// @Desc 结构化输出测试
+package ai_sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newJSONServer 非流式测试服务，第 n 次请求返回 contents[n] 作为回答内容
+func newJSONServer(t *testing.T, contents []string, check func(round int, req ChatCompletionRequest)) *httptest.Server {
+	t.Helper()
+	choices := make([]Choice, len(contents))
+	for i, content := range contents {
+		choices[i] = Choice{Message: Message{Role: assistantRole, Content: content}, FinishReason: "stop"}
+	}
+	return newChoiceServer(t, choices, check)
+}
+
+// newChoiceServer 非流式测试服务，第 n 次请求返回 choices[n]
+func newChoiceServer(t *testing.T, choices []Choice, check func(round int, req ChatCompletionRequest)) *httptest.Server {
+	t.Helper()
+	var round int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&round, 1)) - 1
+		var req ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request err: %v", err)
+		}
+		if check != nil {
+			check(n, req)
+		}
+		if n >= len(choices) {
+			t.Errorf("unexpected request round %d", n)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resp := map[string]interface{}{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"choices": []Choice{choices[n]},
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestSendJSON(t *testing.T) {
+	server := newJSONServer(t, []string{
+		"好的，结果如下：\n```json\n{\"name\":\"六花\",\"age\":16}\n```",
+		`{"name":"六花","nickname":null,"age":16,"tags":["中二"],"address":{"city":"泉州"},"history":[]}`,
+	}, func(round int, req ChatCompletionRequest) {
+		if req.ResponseFormat == nil || req.ResponseFormat.Type != responseFormatJSONSchema ||
+			req.ResponseFormat.JSONSchema.Name != "testPerson" || !req.ResponseFormat.JSONSchema.Strict {
+			t.Errorf("round %d response_format = %+v", round, req.ResponseFormat)
+		}
+		if round == 1 {
+			last := req.Messages[len(req.Messages)-1]
+			if last.Role != userRole || !strings.Contains(last.Content, "$.nickname is required") {
+				t.Errorf("retry message = %+v, want validation error feedback", last)
+			}
+		}
+	})
+	defer server.Close()
+
+	got, err := SendJSON[testPerson](*newTestClient(server.URL), Request{
+		Messages: []Message{{Role: userRole, Content: "介绍一下六花"}},
+	})
+	if err != nil {
+		t.Fatalf("SendJSON() error = %v", err)
+	}
+	if got.Name != "六花" || got.Age != 16 || got.Address.City != "泉州" || len(got.Tags) != 1 {
+		t.Errorf("SendJSON() = %+v", got)
+	}
+}
@@ -0,0 +1,201 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午6:20:00
+// @Desc 密钥隔离：返回 401、insufficient_quota、account_deactivated 的密钥被自动停用，记录原因与时间，可查询与重新启用，无需重启进程
+package ai_sdk
+
+import (
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 隔离原因
+const (
+	QuarantineUnauthorized       = "unauthorized"        // 401，密钥无效或已吊销
+	QuarantineInsufficientQuota  = "insufficient_quota"  // 额度已用尽
+	QuarantineAccountDeactivated = "account_deactivated" // 账户已停用
+	QuarantineManual             = "manual"              // 通过 DisableKey 手动停用
+)
+
+// KeyStatus 密钥状态
+type KeyStatus struct {
+	Endpoint    int       // ApiCfgList 中的下标
+	KeyIndex    int       // AuthList 中的下标，与 Endpoint 一起用于 EnableKeyAt / DisableKeyAt
+	Url         string    // api 地址
+	Key         string    // 脱敏后的密钥
+	Quarantined bool      // 是否已被隔离 (请求时跳过)
+	Reason      string    // 隔离原因
+	Message     string    // 隔离时接口返回的错误信息
+	Since       time.Time // 隔离时间
+}
+
+// KeyQuarantineFunc 密钥被隔离时的回调，在发起请求的 goroutine 中执行，应尽快返回
+// 通过 WithKeyQuarantineHandler 在构造时设置，之后不可修改，客户端的副本共用同一回调
+type KeyQuarantineFunc func(status KeyStatus)
+
+// keyQuarantine 密钥的隔离状态，零值为未隔离
+type keyQuarantine struct {
+	mu      sync.Mutex
+	active  bool
+	reason  string
+	message string
+	since   time.Time
+}
+
+// set 隔离密钥，已处于隔离状态时返回 false
+func (q *keyQuarantine) set(reason string, message string, now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.active {
+		return false
+	}
+	q.active, q.reason, q.message, q.since = true, reason, message, now
+	return true
+}
+
+// clear 解除隔离，未处于隔离状态时返回 false
+func (q *keyQuarantine) clear() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.active {
+		return false
+	}
+	q.active, q.reason, q.message, q.since = false, "", "", time.Time{}
+	return true
+}
+
+func (q *keyQuarantine) quarantined() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.active
+}
+
+func (q *keyQuarantine) fill(status *KeyStatus) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	status.Quarantined, status.Reason, status.Message, status.Since = q.active, q.reason, q.message, q.since
+}
+
+// quarantineReason 根据响应判断是否需要隔离密钥，返回隔离原因，不需要时返回 ""
+func quarantineReason(status int, apiErr RespError) string {
+	for _, code := range []string{apiErr.RawCode, apiErr.Type} {
+		switch code {
+		case QuarantineInsufficientQuota, QuarantineAccountDeactivated:
+			return code
+		}
+	}
+	if status == http.StatusUnauthorized {
+		return QuarantineUnauthorized
+	}
+	return ""
+}
+
+// quarantineKey 隔离密钥并通知回调，密钥已处于隔离状态时不重复通知
+func (a AIClient) quarantineKey(i int, j int, key *keyState, reason string, message string) {
+	if key == nil || !key.quarantine.set(reason, message, time.Now()) {
+		return
+	}
+	status := a.keyStatus(i, j, key)
+	log.Warn().Str("url", status.Url).Str("key", status.Key).Str("reason", reason).Str("message", message).Msg("api key quarantined")
+	if a.onQuarantine != nil {
+		a.onQuarantine(status)
+	}
+}
+
+func (a AIClient) keyStatus(i int, j int, key *keyState) KeyStatus {
+	status := KeyStatus{Endpoint: i, KeyIndex: j, Url: a.endpoints[i].url, Key: maskKey(key.auth)}
+	key.quarantine.fill(&status)
+	return status
+}
+
+// KeyStatuses 所有密钥的状态，与 ApiCfgList、AuthList 的顺序一致 (构造后追加的密钥不统计)
+func (a AIClient) KeyStatuses() []KeyStatus {
+	var statuses []KeyStatus
+	for i := range a.endpoints {
+		for j, key := range a.endpoints[i].keys {
+			statuses = append(statuses, a.keyStatus(i, j, key))
+		}
+	}
+	return statuses
+}
+
+// EnableKey 重新启用被隔离的密钥，key 为完整密钥 (同一密钥出现在多个 api 配置中时全部启用)，有密钥被重新启用时返回 true
+// 脱敏密钥可能对应多个密钥，不能用于定位，需按 KeyStatuses 返回的下标使用 EnableKeyAt
+func (a AIClient) EnableKey(key string) bool {
+	enabled := false
+	for _, k := range a.matchKeys(key) {
+		enabled = enableKey(k) || enabled
+	}
+	return enabled
+}
+
+// EnableKeyAt 重新启用第 endpoint 个 api 配置的第 index 个密钥 (即 KeyStatus 的 Endpoint 与 KeyIndex)，密钥被重新启用时返回 true
+func (a AIClient) EnableKeyAt(endpoint int, index int) bool {
+	k := a.keyAt(endpoint, index)
+	return k != nil && enableKey(k)
+}
+
+// DisableKey 手动隔离密钥，key 为完整密钥 (同一密钥出现在多个 api 配置中时全部隔离)，有密钥被隔离时返回 true
+func (a AIClient) DisableKey(key string, message string) bool {
+	disabled := false
+	for _, k := range a.matchKeys(key) {
+		disabled = k.quarantine.set(QuarantineManual, message, time.Now()) || disabled
+	}
+	return disabled
+}
+
+// DisableKeyAt 手动隔离第 endpoint 个 api 配置的第 index 个密钥 (即 KeyStatus 的 Endpoint 与 KeyIndex)，密钥被隔离时返回 true
+func (a AIClient) DisableKeyAt(endpoint int, index int, message string) bool {
+	k := a.keyAt(endpoint, index)
+	return k != nil && k.quarantine.set(QuarantineManual, message, time.Now())
+}
+
+func enableKey(k *keyState) bool {
+	if !k.quarantine.clear() {
+		return false
+	}
+	log.Info().Str("key", maskKey(k.auth)).Msg("api key re-enabled")
+	return true
+}
+
+// keyAt 第 i 个 api 配置的第 j 个密钥，下标越界时返回 nil
+func (a AIClient) keyAt(i int, j int) *keyState {
+	if i < 0 || j < 0 {
+		return nil
+	}
+	return a.endpointAt(i).key(j)
+}
+
+func (a AIClient) matchKeys(key string) []*keyState {
+	var keys []*keyState
+	for i := range a.endpoints {
+		for _, k := range a.endpoints[i].keys {
+			if keyMatches(k.auth, key) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+// keyMatches 密钥是否与完整密钥匹配 (忽略 Bearer 前缀)
+func keyMatches(auth string, key string) bool {
+	key = strings.TrimPrefix(key, "Bearer ")
+	return key != "" && strings.TrimPrefix(auth, "Bearer ") == key
+}
+
+// quarantinedKeys 被隔离的密钥数
+func (a AIClient) quarantinedKeys() int {
+	n := 0
+	for i := range a.endpoints {
+		for _, k := range a.endpoints[i].keys {
+			if k.quarantine.quarantined() {
+				n++
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,194 @@
+package ai_sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Clov614/go-ai-sdk/config"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestQuarantineReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		apiErr RespError
+		want   string
+	}{
+		{name: "unauthorized", status: 401, want: QuarantineUnauthorized},
+		{name: "account deactivated", status: 401, apiErr: RespError{RawCode: "account_deactivated"}, want: QuarantineAccountDeactivated},
+		{name: "insufficient quota code", status: 429, apiErr: RespError{RawCode: "insufficient_quota"}, want: QuarantineInsufficientQuota},
+		{name: "insufficient quota type", status: 403, apiErr: RespError{Type: "insufficient_quota"}, want: QuarantineInsufficientQuota},
+		{name: "rate limited", status: 429, apiErr: RespError{RawCode: "rate_limit_exceeded"}, want: ""},
+		{name: "server error", status: 500, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quarantineReason(tt.status, tt.apiErr); got != tt.want {
+				t.Errorf("quarantineReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRespError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want RespError
+	}{
+		{
+			name: "openai error",
+			body: `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","param":null,"code":"insufficient_quota"}}`,
+			want: RespError{Message: "You exceeded your current quota", Type: "insufficient_quota", RawCode: "insufficient_quota"},
+		},
+		{name: "numeric code", body: `{"error":{"message":"bad","code":40001}}`, want: RespError{Message: "bad", Code: 40001, RawCode: "40001"}},
+		{name: "top level error", body: `{"message":"bad","code":"oops"}`, want: RespError{Message: "bad", RawCode: "oops"}},
+		{name: "not json", body: `<html>bad gateway</html>`, want: RespError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRespError([]byte(tt.body)); got != tt.want {
+				t.Errorf("parseRespError() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	var respErr RespError
+	if err := json.Unmarshal([]byte(`{"code":true}`), &respErr); err == nil {
+		t.Errorf("RespError accepted a boolean code")
+	}
+}
+
+func TestAIClient_KeyQuarantine(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		mu.Lock()
+		calls[auth]++
+		mu.Unlock()
+		switch auth {
+		case "Bearer sk-revoked-0001":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`))
+		case "Bearer sk-noquota-0002":
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`))
+		default:
+			_, _ = w.Write([]byte(okBody))
+		}
+	}))
+	defer server.Close()
+	var notified []KeyStatus
+	a, err := NewClient([]config.APIConfig{{Url: server.URL, AuthList: []string{"sk-revoked-0001", "sk-noquota-0002", "sk-good-0003"}}},
+		WithKeyQuarantineHandler(func(status KeyStatus) { notified = append(notified, status) }))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	send := func() error {
+		_, err := a.Send(Request{Messages: []Message{{Role: userRole, Content: "hi"}}})
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		if err = send(); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if calls["Bearer sk-revoked-0001"] != 1 || calls["Bearer sk-noquota-0002"] != 1 || calls["Bearer sk-good-0003"] != 3 {
+		t.Errorf("calls = %v, want quarantined keys used once and not retried", calls)
+	}
+	if len(notified) != 2 || notified[0].Reason != QuarantineUnauthorized || notified[1].Reason != QuarantineInsufficientQuota {
+		t.Fatalf("notified = %+v, want unauthorized and insufficient_quota", notified)
+	}
+	statuses := a.KeyStatuses()
+	if !statuses[1].Quarantined || statuses[1].Key != "sk-...0002" || statuses[1].Message != "You exceeded your current quota" || statuses[1].Since.IsZero() {
+		t.Errorf("status = %+v, want quarantined with message and time", statuses[1])
+	}
+	if statuses[2].Quarantined {
+		t.Errorf("good key quarantined: %+v", statuses[2])
+	}
+
+	// 按 KeyStatuses 的下标重新启用，密钥仍无效时再次被隔离并通知
+	if a.EnableKey("sk-...0001") {
+		t.Fatalf("EnableKey() should not match a masked key")
+	}
+	if !a.EnableKeyAt(statuses[0].Endpoint, statuses[0].KeyIndex) || a.EnableKeyAt(statuses[0].Endpoint, statuses[0].KeyIndex) {
+		t.Fatalf("EnableKeyAt() should re-enable the key exactly once")
+	}
+	if err = send(); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if calls["Bearer sk-revoked-0001"] != 2 || len(notified) != 3 {
+		t.Errorf("re-enabled key calls = %d, notified = %d, want 2 and 3", calls["Bearer sk-revoked-0001"], len(notified))
+	}
+
+	if !a.DisableKey("sk-good-0003", "rotating") {
+		t.Fatalf("DisableKey() = false")
+	}
+	if err = send(); !errors.Is(err, keyQuarantinedErr) {
+		t.Errorf("Send() error = %v, want keyQuarantinedErr", err)
+	}
+	if status := a.KeyStatuses()[2]; status.Reason != QuarantineManual || status.Message != "rotating" {
+		t.Errorf("manual status = %+v", status)
+	}
+}
+
+func TestAIClient_DisableKey(t *testing.T) {
+	// 前两个密钥脱敏后相同 (sk-...0001)，短密钥脱敏后为 ****
+	cfgs := []config.APIConfig{
+		{Url: "http://127.0.0.1:1", AuthList: []string{"sk-aaaa-0001", "sk-bbbb-0001", "sk-short"}},
+		{Url: "http://127.0.0.1:2", AuthList: []string{"sk-aaaa-0001"}},
+	}
+	tests := []struct {
+		name    string
+		disable func(a *AIClient) bool
+		want    []bool // 按 KeyStatuses 顺序的隔离状态
+	}{
+		{name: "full key", disable: func(a *AIClient) bool { return a.DisableKey("sk-bbbb-0001", "") }, want: []bool{false, true, false, false}},
+		{name: "bearer full key", disable: func(a *AIClient) bool { return a.DisableKey("Bearer sk-bbbb-0001", "") }, want: []bool{false, true, false, false}},
+		{name: "key in several endpoints", disable: func(a *AIClient) bool { return a.DisableKey("sk-aaaa-0001", "") }, want: []bool{true, false, false, true}},
+		{name: "masked key", disable: func(a *AIClient) bool { return a.DisableKey("sk-...0001", "") }, want: []bool{false, false, false, false}},
+		{name: "masked short key", disable: func(a *AIClient) bool { return a.DisableKey("****", "") }, want: []bool{false, false, false, false}},
+		{name: "empty key", disable: func(a *AIClient) bool { return a.DisableKey("", "") }, want: []bool{false, false, false, false}},
+		{name: "index", disable: func(a *AIClient) bool { return a.DisableKeyAt(1, 0, "") }, want: []bool{false, false, false, true}},
+		{name: "index out of range", disable: func(a *AIClient) bool {
+			return a.DisableKeyAt(0, 3, "") || a.DisableKeyAt(2, 0, "") || a.DisableKeyAt(-1, 0, "")
+		}, want: []bool{false, false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewClient(cfgs)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			wantDisabled := false
+			for _, w := range tt.want {
+				wantDisabled = wantDisabled || w
+			}
+			if got := tt.disable(a); got != wantDisabled {
+				t.Errorf("disable = %v, want %v", got, wantDisabled)
+			}
+			for i, status := range a.KeyStatuses() {
+				if status.Quarantined != tt.want[i] {
+					t.Errorf("key %d (endpoint %d index %d) quarantined = %v, want %v", i, status.Endpoint, status.KeyIndex, status.Quarantined, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDoSend_ErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"error":{"message":"model not found","type":"invalid_request_error","code":"model_not_found"}}`))
+	}))
+	defer server.Close()
+	resp, err := newTestClient(server.URL).Send(Request{Messages: []Message{{Role: userRole, Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got := resp.Err(); got.RawCode != "model_not_found" || got.Message != "model not found" {
+		t.Errorf("Err() = %+v, want model_not_found", got)
+	}
+}
@@ -16,31 +16,41 @@ import (
 
 var (
 	logfile *os.File
+	logPath string
 	once    sync.Once
 )
 
 const (
-	logPath = "./log/ai.log"
+	DefaultLogPath = "./log/ai.log" // 默认日志文件
 )
 
-func init() {
+// Setup 将日志同时输出到控制台与 path 文件，目录不存在时自动创建
+// 导入本包不会创建任何文件，需要写入日志文件时显式调用
+func Setup(path string) error {
 	zerolog.TimeFieldFormat = "2006-01-02 15:04:05"
-	zerolog.MultiLevelWriter(zerolog.ConsoleWriter{Out: os.Stderr})
-	_, err := validLogPath(logPath, true)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to open log file")
+	if _, err := validLogPath(path, true); err != nil {
+		return err
 	}
-	logfile, err = os.OpenFile(logPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
-		log.Error().Msgf("Error opening file: %v", err)
+		return fmt.Errorf("error opening log file: %w", err)
+	}
+	if logfile != nil {
+		_ = logfile.Close()
 	}
+	logfile, logPath = file, path
 	multi := zerolog.MultiLevelWriter(zerolog.ConsoleWriter{Out: os.Stderr}, logfile)
 
 	log.Logger = zerolog.New(multi).With().Timestamp().Logger()
+	return nil
 }
 
 // MonitorLogSize checks the log file size and truncates it if it eceeds the max size.
+// 未调用 Setup 时直接返回
 func MonitorLogSize(maxLogsize int64) {
+	if logfile == nil {
+		return
+	}
 	for {
 		// Get the current log file size
 		fi, err := logfile.Stat()
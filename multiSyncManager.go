@@ -5,8 +5,13 @@
 package ai_sdk
 
 import (
+	"container/list"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/Clov614/go-ai-sdk/config"
+	"github.com/rs/zerolog/log"
+	"io"
 	"sync"
 	"time"
 )
@@ -21,12 +26,34 @@ const (
 // Session 会话主体 （k-v 会话id-会话信息）
 type Session struct {
 	globalSurvivalLimit time.Duration
-	systemContent       string // 预设消息
+	systemContent       string            // 预设消息
+	params              config.ChatParams // 会话默认生成参数
+	maxToolRounds       int               // 单次对话最多执行的工具轮数，0 使用配置
+	toolLoopTimeout     time.Duration     // 单次对话工具调用最长耗时，0 使用配置
+	toolWorkers         int               // 同一轮中并发执行的工具数，0 使用配置
+	toolTimeout         time.Duration     // 单个工具调用超时时间，0 使用配置
+	store               SessionStore      // 会话持久化存储 (可选)
+	summarizer          Summarizer        // 滚动摘要记忆 (可选)
 	cache               map[string]*sessionInfo
+	lru                 list.List      // 会话使用顺序，最近使用的在前
+	maxSessions         int            // 最多保留的会话数，0 为不限制
+	maxSessionBytes     int            // 单个会话问答记录的最大字节数，0 为不限制
+	onEvict             EvictionFunc   // 会话或问答被自动移除时的回调 (可选)
+	client              *AIClient      // 会话主体使用的客户端，nil 时使用全局客户端
+	closed              bool           // 已关闭，不再创建会话
+	ownedBySDK          bool           // 由 ai_sdk.New 创建，由 SDK 的持有者负责关闭
+	aiCfg               *config.AICfg  // 会话主体的配置 (ai_sdk.New 创建时为 SDK 的配置)，nil 时使用全局 config.Config
+	wg                  sync.WaitGroup // 存活计时 goroutine
 	mu                  sync.RWMutex
 }
 
+// NewSession 创建会话主体，问答轮数、工具调用、上下文窗口等限制读取全局 config.Config
 func NewSession(systemSet string, persessionTimeOut int) *Session {
+	return newSessionWithConfig(systemSet, persessionTimeOut, nil)
+}
+
+// newSessionWithConfig 创建会话主体，cfg 不为 nil 时限制取自 cfg，不受之后修改 config.Config 或再次调用 ai_sdk.New 的影响
+func newSessionWithConfig(systemSet string, persessionTimeOut int, cfg *config.AICfg) *Session {
 	sessionTimeOut := time.Duration(persessionTimeOut) * time.Minute
 	if sessionTimeOut < 2*time.Minute {
 		sessionTimeOut = 2 * time.Minute
@@ -35,91 +62,174 @@ func NewSession(systemSet string, persessionTimeOut int) *Session {
 		globalSurvivalLimit: sessionTimeOut,
 		systemContent:       systemSet,
 		cache:               make(map[string]*sessionInfo, 10),
+		aiCfg:               cfg,
 	}
+	s.maxSessions = s.cfg().MaxSessions
+	s.maxSessionBytes = s.cfg().MaxSessionBytes
 	return &s
 }
 
+// cfg 会话主体生效的配置，可在 nil 上调用
+func (s *Session) cfg() config.AICfg {
+	if s == nil || s.aiCfg == nil {
+		return config.Config
+	}
+	return *s.aiCfg
+}
+
 // 会话信息
 type sessionInfo struct {
+	session        *Session      // 所属会话主体
 	sessionId      string        // 会话唯一id
 	history        *history      // history: 上下文
 	startTime      time.Time     // 会话时间信息
 	survivalLimit  time.Duration // 会话存活时间(存活周期)
-	survivalSignal chan struct{} // 存活信号量
+	survivalSignal chan struct{} // 存活信号量 (缓冲为 1，发送不阻塞)
 	done           chan struct{} // 结束信号
+	stopOnce       sync.Once
+	elem           *list.Element     // 在 Session.lru 中的位置
+	model          string            // 会话使用的模型，为空时使用 client 的模型
+	client         *AIClient         // 会话使用的客户端，nil 时使用全局客户端
+	params         config.ChatParams // 会话生成参数，覆盖 Session.Params
+	allowedTools   map[string]bool   // 允许使用的工具，nil 表示不限制
 }
 
-// GetSession 获取唯一会话
-func (s *Session) GetSession(sessionId string, extraOp func() string) *sessionInfo {
-	s.mu.RLock()
+// GetSession 获取唯一会话，opts 为会话级配置 (模型、预设、生成参数、工具、客户端)，仅在创建会话时生效
+func (s *Session) GetSession(sessionId string, extraOp func() string, opts ...SessionOption) *sessionInfo {
+	s.mu.Lock()
 	if info, ok := s.cache[sessionId]; ok { // 判断会话列表中是否存在该id（存在则不创建，直接返回）
-		s.mu.RUnlock()
+		s.lru.MoveToFront(info.elem)
+		s.mu.Unlock()
 		return info
 	}
-	s.mu.RUnlock()
-	return s.newSession(sessionId, extraOp)
+	s.mu.Unlock()
+	return s.newSession(sessionId, extraOp, opts...)
 }
 
-// 新创建的会话启动计时器，并通过存活信号量刷新计时器，超时移除该会话(注意goroutine泄露问题)
-func (s *Session) newSession(sessionId string, extraOp func() string) *sessionInfo {
+// 新创建的会话启动计时器，并通过存活信号量刷新计时器，超时移除该会话
+// 设置了持久化存储时优先从存储中恢复会话；会话主体关闭后返回的会话无法对话
+// 会话数超出上限时移除最久未使用的会话
+func (s *Session) newSession(sessionId string, extraOp func() string, opts ...SessionOption) *sessionInfo {
+	info, evicted := s.createSession(sessionId, extraOp, opts)
+	for _, e := range evicted {
+		s.notifyEvict(EvictCapacity, e)
+	}
+	return info
+}
+
+// createSession 创建并缓存会话，返回因会话数超出上限被移除的会话
+func (s *Session) createSession(sessionId string, extraOp func() string, opts []SessionOption) (info *sessionInfo, evicted []*sessionInfo) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if info, ok := s.cache[sessionId]; ok { // 并发创建时复用已创建的会话
+		s.lru.MoveToFront(info.elem)
+		return info, nil
+	}
+	var o sessionOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
 	var sysInfo string
 	sysInfo = s.systemContent
+	if o.system != nil {
+		sysInfo = *o.system
+	}
 	if nil != extraOp {
 		sysInfo += "\n" + extraOp() // 预设增加额外信息
 	}
-	info := &sessionInfo{
+	info = &sessionInfo{
+		session:        s,
 		sessionId:      sessionId,
-		history:        newHistory(sysInfo), // 注册消息历史记录
+		history:        newHistory(sysInfo, s.cfg().HistoryNum), // 注册消息历史记录
 		startTime:      time.Now(),
 		survivalLimit:  s.globalSurvivalLimit,
-		survivalSignal: make(chan struct{}),
+		survivalSignal: make(chan struct{}, 1),
 		done:           make(chan struct{}),
+		model:          o.model,
+		client:         o.client,
+		params:         o.params,
+		allowedTools:   o.allowedTools,
+	}
+	info.history.sessionId = sessionId
+	info.history.countTokens = messageTokenCounter(info.modelName())
+	info.history.countTools = toolsTokenCounter(info.modelName())
+	info.history.selectTools = info.selectTools
+	info.history.startTime = info.startTime
+	info.history.store = s.store
+	info.history.done = info.done
+	if summarizer := s.summarizer; summarizer != nil { // 使用会话的客户端与模型生成摘要
+		info.history.summarize = func(ctx context.Context, summary string, evicted []Message) (string, error) {
+			return summarizer(ctx, info.aiClient(), info.modelName(), summary, evicted)
+		}
+	}
+	info.history.maxBytes = s.maxSessionBytes
+	info.history.onEvict = s.onEvict
+	if s.closed {
+		info.stop()
+		return info, nil
+	}
+	if s.store != nil {
+		s.restoreSession(info)
 	}
 	s.cache[sessionId] = info
+	info.elem = s.lru.PushFront(info)
+	s.wg.Add(1)
 	go s.checkSurvival(info) // 超时检测
-	return info
+	return info, s.evictOverflow()
 }
 
-// 超时检测
+// 超时检测，会话超时或被停止 (Delete / Close) 时退出
 func (s *Session) checkSurvival(info *sessionInfo) {
-	var once sync.Once
-	go func() {
-		timer := time.NewTimer(info.survivalLimit)
-		defer timer.Stop()
-		for {
-			select {
-			case <-info.survivalSignal: // 重置计时器
-				if !timer.Stop() {
-					<-timer.C
-				}
-				timer.Reset(info.survivalLimit)
-			case <-timer.C: // 超时删除会话
-				once.Do(func() {
-					s.removeById(info.sessionId)
-				})
-				return
-			case <-info.done:
-				once.Do(func() {
-					s.removeById(info.sessionId)
-				})
-				return
+	defer s.wg.Done()
+	timer := time.NewTimer(info.survivalLimit)
+	defer timer.Stop()
+	for {
+		select {
+		case <-info.survivalSignal: // 重置计时器
+			if !timer.Stop() {
+				<-timer.C
 			}
+			timer.Reset(info.survivalLimit)
+		case <-timer.C: // 超时将会话移出内存
+			s.expire(info)
+			return
+		case <-info.done:
+			return
 		}
-	}()
+	}
 }
 
 // TalkById 根据会话id对话 新增会话来获取会话发起对话
 func (s *Session) TalkById(sessionId string, content string) (string, error) {
+	return s.TalkByIdWithContext(context.Background(), sessionId, content)
+}
+
+// TalkByIdWithContext 携带 context 根据会话id对话
+func (s *Session) TalkByIdWithContext(ctx context.Context, sessionId string, content string) (string, error) {
 	sessioninfo := s.GetSession(sessionId, nil)
-	return sessioninfo.Talk(content)
+	return sessioninfo.TalkWithContext(ctx, content)
 }
 
 // TalkByIdEx 根据会话id对话 并且允许携带 Ex: 额外的预设信息(拼接入system预设)
 func (s *Session) TalkByIdEx(sessionId string, content string, extraOp func() string) (string, error) {
+	return s.TalkByIdExWithContext(context.Background(), sessionId, content, extraOp)
+}
+
+// TalkByIdExWithContext 携带 context 根据会话id对话 并且允许携带额外的预设信息
+func (s *Session) TalkByIdExWithContext(ctx context.Context, sessionId string, content string, extraOp func() string) (string, error) {
 	sessioninfo := s.GetSession(sessionId, extraOp)
-	return sessioninfo.Talk(content)
+	return sessioninfo.TalkWithContext(ctx, content)
+}
+
+// TalkStreamById 根据会话id发起流式对话，onDelta 按到达顺序接收增量内容
+func (s *Session) TalkStreamById(sessionId string, content string, onDelta func(delta string)) (string, error) {
+	return s.TalkStreamByIdWithContext(context.Background(), sessionId, content, onDelta)
+}
+
+// TalkStreamByIdWithContext 携带 context 根据会话id发起流式对话
+func (s *Session) TalkStreamByIdWithContext(ctx context.Context, sessionId string, content string, onDelta func(delta string)) (string, error) {
+	sessioninfo := s.GetSession(sessionId, nil)
+	return sessioninfo.TalkStreamWithContext(ctx, content, onDelta)
 }
 
 // IsExist 该对话是否存在
@@ -140,54 +250,69 @@ func (s *Session) ResetPreset(preset string) {
 	s.systemContent = preset
 }
 
+// SetParams 设置会话默认生成参数，覆盖 config.AICfg 中的同名参数，可被单次请求参数覆盖
+func (s *Session) SetParams(params config.ChatParams) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.params = params
+}
+
+// Params 获取会话默认生成参数
+func (s *Session) Params() config.ChatParams {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.params
+}
+
+// tokenBudget 当前会话请求上下文可用的 token 数
+func (s *sessionInfo) tokenBudget() int {
+	params := s.aiClient().Params
+	if s.session != nil {
+		params = params.Merge(s.session.Params())
+	}
+	return tokenBudget(s.modelName(), s.session.cfg().ContextWindow, params.Merge(s.params))
+}
+
+// newRequest 构造携带会话默认生成参数的请求
+func (s *sessionInfo) newRequest(msgs []Message, tools *[]Tool) Request {
+	req := Request{Model: s.model, Messages: msgs}
+	if tools != nil && len(*tools) != 0 {
+		req.Tools = tools
+		req.ToolChoice = "auto"
+	}
+	if s.session != nil {
+		req.defaults = s.session.Params()
+	}
+	req.defaults = req.defaults.Merge(s.params)
+	return req
+}
+
 // Talk 对该sessionInfo 发起对话
 func (s *sessionInfo) Talk(content string) (string, error) {
-	go func() {
-		s.survivalSignal <- struct{}{} // 确保在会话期间存活
-	}()
-	answers, err := s.history.handleQuestion(content, func(msgs answerList, tools *[]Tool) (retAnswers answerList, err error) {
-		if tools != nil && len(*tools) != 0 { // 发起 function_call // todo 重构这部分
-			funcCallResp, err := aiclient.Send(Request{Messages: msgs, Tools: tools, ToolChoice: "auto"})
+	return s.TalkWithContext(context.Background(), content)
+}
+
+// TalkWithContext 携带 context 对该sessionInfo 发起对话，ctx 同时作用于模型请求与工具调用
+// 模型可连续多轮调用工具，轮数与耗时上限见 Session.SetToolLoopLimit，中间的工具调用消息会一并写入 history
+func (s *sessionInfo) TalkWithContext(ctx context.Context, content string) (string, error) {
+	if s.isClosed() {
+		return "", sessionClosedErr
+	}
+	s.keepAlive() // 确保在会话期间存活
+	answers, err := s.history.handleQuestion(ctx, content, s.tokenBudget(), func(msgs answerList, tools *[]Tool) (retAnswers answerList, err error) {
+		return s.runToolLoop(ctx, msgs, tools, func(ctx context.Context, req Request) (answer Message, finishReason string, err error) {
+			resp, err := s.aiClient().SendWithContext(ctx, req)
 			if err != nil {
-				return retAnswers, fmt.Errorf("function call aiclient.Send err: %w", err)
-			}
-			var answer Message
-			answer.Role = assistantRole
-			answer.Content = funcCallResp.data.Choices[0].Message.Content
-			answer.ToolCalls = funcCallResp.data.Choices[0].Message.ToolCalls
-			if funcCallResp.data.Choices[0].FinishReason != ToolsCallFinishReason { // 不是调用回调方法
-				return answerList{answer}, nil // 普通返回 返回一条答案
+				return answer, "", fmt.Errorf("aiclient.Send err: %w", err)
 			}
-			retAnswers = append(retAnswers, answer) // tool answer
-			for _, call := range answer.ToolCalls {
-				callInfo := FuncRegister.GetCallInfo(call.Function.Name)
-				toolMsg, err := callInfo.Call(call.ID, call.Function.Arguments) // 请求外部函数
-				if err != nil {
-					return retAnswers, fmt.Errorf("function call call err: %w", err)
-				}
-				retAnswers = append(retAnswers, toolMsg) // 将tools答案添加回 msg—history
+			choices := resp.GetData().Choices
+			if len(choices) == 0 {
+				return answer, "", emptyChoicesErr
 			}
-			// 携带tools上下文再次请求
-			funcCallResp, err = aiclient.Send(Request{Messages: msgs, Tools: nil, ToolChoice: ""})
-			if err != nil {
-				return retAnswers, fmt.Errorf("function call aiclient.Send second err: %w", err)
-			}
-			answer.ToolCalls = nil
+			answer = choices[0].Message
 			answer.Role = assistantRole
-			answer.Content = funcCallResp.data.Choices[0].Message.Content
-			retAnswers = append(retAnswers, answer)
-			return retAnswers, nil
-		}
-		resp, err := aiclient.Send(Request{Messages: msgs})
-		if err != nil {
-			return retAnswers, fmt.Errorf("aiclient.Send err: %w", err)
-		}
-		var answer Message
-		answer.Role = assistantRole
-		answer.Content = resp.data.Choices[0].Message.Content
-		answer.ToolCalls = nil // 第三个answer ToolCalls置空
-		retAnswers = append(retAnswers, answer)
-		return retAnswers, nil
+			return answer, choices[0].FinishReason, nil
+		})
 	})
 	if err != nil {
 		return "", fmt.Errorf("talkById err: %w", err)
@@ -195,32 +320,133 @@ func (s *sessionInfo) Talk(content string) (string, error) {
 	return answers[len(answers)-1].Content, nil
 }
 
-// 移除会话
+// TalkStream 对该sessionInfo 发起流式对话，增量内容通过 onDelta 回调，完整回答仍会写入 history
+// 命中工具调用时会拼接流式返回的 tool_calls 片段并执行，随后继续流式请求，直到输出最终回答
+func (s *sessionInfo) TalkStream(content string, onDelta func(delta string)) (string, error) {
+	return s.TalkStreamWithContext(context.Background(), content, onDelta)
+}
+
+// TalkStreamWithContext 携带 context 对该sessionInfo 发起流式对话
+func (s *sessionInfo) TalkStreamWithContext(ctx context.Context, content string, onDelta func(delta string)) (string, error) {
+	if s.isClosed() {
+		return "", sessionClosedErr
+	}
+	s.keepAlive() // 确保在会话期间存活
+	answers, err := s.history.handleQuestion(ctx, content, s.tokenBudget(), func(msgs answerList, tools *[]Tool) (retAnswers answerList, err error) {
+		return s.runToolLoop(ctx, msgs, tools, func(ctx context.Context, req Request) (Message, string, error) {
+			return streamAnswer(ctx, s.aiClient(), req, onDelta)
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("talkStream err: %w", err)
+	}
+	return answers[len(answers)-1].Content, nil
+}
+
+// streamAnswer 发起流式请求并将第一个 choice 的增量内容交给 onDelta，返回聚合后的回答
+func streamAnswer(ctx context.Context, client *AIClient, req Request, onDelta func(delta string)) (answer Message, finishReason string, err error) {
+	stream, err := client.SendStreamWithContext(ctx, req)
+	if err != nil {
+		return answer, "", fmt.Errorf("client.SendStreamWithContext err: %w", err)
+	}
+	defer stream.Close()
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return answer, "", fmt.Errorf("stream.Recv err: %w", err)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Index == 0 && choice.Delta.Content != "" && onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+	}
+	choices := stream.Response().data.Choices
+	if len(choices) == 0 {
+		return answer, "", emptyChoicesErr
+	}
+	answer.Role = assistantRole
+	answer.Content = choices[0].Message.Content
+	answer.ToolCalls = choices[0].Message.ToolCalls
+	return answer, choices[0].FinishReason, nil
+}
+
+// restoreSession 从持久化存储恢复会话 concurrent unsafe (需持有 s.mu)
+// 会话超时只会移出内存，存储中的会话无论停机多久都会恢复，需删除时调用 Delete
+func (s *Session) restoreSession(info *sessionInfo) {
+	record, ok, err := s.store.Load(info.sessionId)
+	if err != nil {
+		log.Error().Err(err).Str("sessionId", info.sessionId).Msg("load session failed")
+		return
+	}
+	if !ok {
+		return
+	}
+	info.history.restore(record)
+	info.startTime = record.StartTime
+}
+
+// 移除会话并停止其计时器，同时删除持久化的会话数据
+// 先停止会话并等待进行中的保存完成，之后仍持有该会话的调用方无法对话，也不会将会话写回存储
 func (s *Session) removeById(id string) (ok bool) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	if _, ok = s.cache[id]; ok {
-		delete(s.cache, id)
+	info, ok := s.cache[id]
+	if ok {
+		s.uncache(info)
+	}
+	s.mu.Unlock()
+	if ok {
+		info.stop()
+		info.history.mu.Lock() // 等待进行中的 save，stop 之后的 save 不再写入
+		info.history.mu.Unlock()
+	}
+	s.mu.Lock()
+	s.deleteStored(id)
+	s.mu.Unlock()
+	return ok
+}
+
+// deleteStored 删除持久化的会话数据 concurrent unsafe (需持有 s.mu)
+func (s *Session) deleteStored(id string) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.Delete(id); err != nil {
+		log.Error().Err(err).Str("sessionId", id).Msg("delete session failed")
 	}
-	return
 }
 
 // history 上下文
+// 以问答为单位裁剪：超出 token 预算或问答轮数上限时移除最早的问答，一轮问答中的 tool_calls 回答与 tool 消息不会被拆开
 type history struct {
-	maxHistory int // 最长上下文数量
-	system     Message
-	dialog     []dialogEntry // 问答实体类
-	msgListNum int           // 当前上下文长度
-	msgList    []Message     // 转换后请求用上下文
-	mu         sync.Mutex
+	maxHistory  int // 最多保留的问答轮数，0 为不限制 (仍受 token 预算约束)
+	system      Message
+	dialog      []dialogEntry                // 问答实体类
+	maxBytes    int                          // 问答记录的最大字节数，0 为不限制
+	onEvict     EvictionFunc                 // 问答因字节数超出上限被移除时的回调
+	selectTools func(content string) *[]Tool // 根据问题选择工具，nil 时使用 FuncRegister.GetToolsByContent
+	countTokens func(msg Message) int        // 单条消息的 token 数
+	countTools  func(tools *[]Tool) int      // 工具定义的 token 数
+	sessionId   string                       // 所属会话id (持久化用)
+	startTime   time.Time                    // 会话创建时间 (持久化用)
+	store       SessionStore                 // 会话持久化存储，nil 表示不持久化
+	done        <-chan struct{}              // 会话结束信号，会话结束后不再保存
+	summary     string                       // 被移出问答的滚动摘要
+	summarize   summarizeFunc                // 摘要函数，nil 表示不使用摘要
+	pending     []dialogEntry                // 因问答轮数上限移除、尚未合并进摘要的问答
+	mu          sync.Mutex
 }
 
-func newHistory(system string) *history {
+// newHistory 创建问答记录，maxHistory 为最多保留的问答轮数
+func newHistory(system string, maxHistory int) *history {
 	h := history{
-		maxHistory: config.Config.HistoryNum,
-		dialog:     make([]dialogEntry, 0),
-		msgListNum: 0,
-		msgList:    make([]Message, 0),
+		maxHistory:  maxHistory,
+		dialog:      make([]dialogEntry, 0),
+		countTokens: estimateMessageTokens,
+		countTools:  estimateToolsTokens,
 	}
 	if system != "" {
 		h.system = Message{
@@ -235,21 +461,39 @@ func newHistory(system string) *history {
 type dialogEntry struct {
 	question Message
 	answerList
+	tokens int // 问题与回答的 token 数
+	bytes  int // 问题与回答的字节数
 }
 
 type answerList []Message
 
-// 处理普通问题
-func (h *history) handleQuestion(content string, handleFunc func(msgs answerList, tools *[]Tool) (answers answerList, err error)) (answers answerList, err error) {
-	msgs := h.getMessage()
+// 处理普通问题，budget 为请求上下文 (消息与工具定义) 可用的 token 数
+// 设置了摘要函数时，超出预算被移除的问答会先合并进摘要，再按新的摘要重新裁剪
+func (h *history) handleQuestion(ctx context.Context, content string, budget int, handleFunc func(msgs answerList, tools *[]Tool) (answers answerList, err error)) (answers answerList, err error) {
 	question := Message{
 		Role:    userRole,
 		Content: content,
 	}
-	tools := FuncRegister.GetToolsByContent(content)
+	var tools *[]Tool
+	if h.selectTools != nil {
+		tools = h.selectTools(content)
+	} else {
+		tools = FuncRegister.GetToolsByContent(content)
+	}
 	//if tools != nil {
 	//	question.ToolCalls = tools
 	//}
+	budget -= h.countTools(tools) + h.countTokens(question)
+	evicted := h.trim(budget)
+	if h.summarize != nil {
+		h.foldSummary(ctx, evicted)
+		if evicted = h.trim(budget); len(evicted) != 0 { // 摘要变长后可能再次超出预算，再次移除的问答在下一轮合并
+			h.mu.Lock()
+			h.pending = append(h.pending, evicted...)
+			h.mu.Unlock()
+		}
+	}
+	msgs := h.getMessage()
 	answers, err = handleFunc(append(msgs, question), tools)
 	if err != nil {
 		return answers, fmt.Errorf("handleQuestion handleMessage err: %w", err)
@@ -258,50 +502,96 @@ func (h *history) handleQuestion(content string, handleFunc func(msgs answerList
 	return answers, err
 }
 
+// trim 移除最早的问答，直到预设、摘要与剩余问答的 token 数不超过 budget
+func (h *history) trim(budget int) (evicted []dialogEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	used := 0
+	if h.system.Content != "" { // 预设始终保留
+		used += h.countTokens(h.system)
+	}
+	if summary, ok := h.summaryMessage(); ok { // 摘要始终保留
+		used += h.countTokens(summary)
+	}
+	keep := len(h.dialog)
+	for keep > 0 && used+h.dialog[keep-1].tokens <= budget { // 从最近的问答开始保留
+		used += h.dialog[keep-1].tokens
+		keep--
+	}
+	for i := 0; i < keep; i++ {
+		evicted = append(evicted, h.removeFirst())
+	}
+	if len(evicted) != 0 {
+		log.Debug().Str("sessionId", h.sessionId).Int("evicted", len(evicted)).Int("budget", budget).Msg("history trimmed by token budget")
+		h.save()
+	}
+	return evicted
+}
+
+// entryTokens 计算一轮问答的 token 数
+func (h *history) entryTokens(entry dialogEntry) int {
+	n := h.countTokens(entry.question)
+	for _, answer := range entry.answerList {
+		n += h.countTokens(answer)
+	}
+	return n
+}
+
 // concurrent unsafe 删除最早的一条对话记录
 func (h *history) removeFirst() (removedEntry dialogEntry) {
 	if len(h.dialog) == 0 {
 		return dialogEntry{}
 	}
-
-	removeEntry := (h.dialog)[0]
-	if cap(h.dialog) > 64 {
-		var newDialog []dialogEntry
-		copy(h.dialog[1:], newDialog)
-		h.dialog = newDialog
-		return removeEntry
-	}
+	removedEntry = h.dialog[0]
+	h.dialog[0] = dialogEntry{} // 释放引用
 	h.dialog = h.dialog[1:]
-	return removeEntry
+	return removedEntry
 }
 
+// addLast 追加一轮问答，超出问答轮数或字节数上限时移除最早的问答，因字节数移除的问答交给 onEvict
 func (h *history) addLast(entry dialogEntry) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
-	for len(h.dialog) >= h.maxHistory {
-		h.removeFirst()
+	entry.tokens = h.entryTokens(entry)
+	entry.bytes = entryBytes(entry)
+	for h.maxHistory > 0 && len(h.dialog) >= h.maxHistory {
+		removed := h.removeFirst()
+		if h.summarize != nil {
+			h.pending = append(h.pending, removed)
+		}
 	}
 	h.dialog = append(h.dialog, entry)
+	dropped := h.trimBytes()
+	if h.summarize != nil {
+		h.pending = append(h.pending, dropped...)
+	}
+	h.save()
+	onEvict := h.onEvict
+	var record SessionRecord
+	if len(dropped) != 0 && onEvict != nil {
+		record = h.record()
+		record.Dialogs = dialogRecords(dropped)
+	}
+	h.mu.Unlock()
+	if len(record.Dialogs) != 0 {
+		onEvict(EvictMessageBytes, record)
+	}
 }
 
+// getMessage 返回请求用上下文的副本：预设、摘要与全部问答
 func (h *history) getMessage() (msg []Message) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	dialogLen := len(h.dialog)
-	if dialogLen == 0 {
-		h.msgList = append(h.msgList, h.system)
+	if h.system.Content != "" {
+		msg = append(msg, h.system)
 	}
-	if h.msgListNum == dialogLen { // 长度相同，直接返回
-		return h.msgList
+	if summary, ok := h.summaryMessage(); ok {
+		msg = append(msg, summary)
 	}
-	for h.msgListNum < dialogLen {
-		// 添加会话
-		h.msgList = append(h.msgList, h.dialog[h.msgListNum].question)
-		h.msgList = append(h.msgList, h.dialog[h.msgListNum].answerList...)
-		h.msgListNum++
+	for _, entry := range h.dialog {
+		msg = append(msg, entry.question)
+		msg = append(msg, entry.answerList...)
 	}
-	// 返回一个 h.msgList 的副本
-	return append([]Message(nil), h.msgList...)
+	return msg
 }
 
 //func (h *history) removeLastQuestion() (question Message, ok bool) {
@@ -316,18 +606,9 @@ func (h *history) getMessage() (msg []Message) {
 //	return
 //}
 
+// DefaultSession 默认会话主体 (无预设)，ai_sdk.New / Init 会替换为新创建的会话主体
 var DefaultSession *Session
 
-const defaultSystemSet = "从现在开始，我需要你扮演小鸟游六花这个动漫角色，语气跟说话逻辑都要尽力模仿，要完美融入这个角色的设定中。我会称呼你为rikka或六花，届时你明白是在称呼你。"
-
 func init() {
-	sessionTimeOut := time.Duration(config.Config.SessionTimeOut) * time.Minute
-	if sessionTimeOut < 2*time.Minute {
-		sessionTimeOut = 2 * time.Minute
-	}
-	DefaultSession = &Session{
-		globalSurvivalLimit: sessionTimeOut,
-		systemContent:       defaultSystemSet,
-		cache:               make(map[string]*sessionInfo, 10),
-	}
+	DefaultSession = NewSession("", config.Config.SessionTimeOut)
 }
@@ -5,11 +5,14 @@
 package ai_sdk
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/Clov614/go-ai-sdk/config"
 	"github.com/Clov614/go-ai-sdk/example_func_call/weather"
 	"github.com/Clov614/go-ai-sdk/global"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -162,10 +165,9 @@ func Test_session_TalkById(t *testing.T) {
 
 func Test_history_addLast(t *testing.T) {
 	h := &history{
-		maxHistory: 10,
-		dialog:     make([]dialogEntry, 0),
-		msgListNum: 0,
-		msgList:    make([]Message, 0),
+		maxHistory:  10,
+		dialog:      make([]dialogEntry, 0),
+		countTokens: estimateMessageTokens,
 	}
 	type args struct {
 		entry dialogEntry
@@ -211,6 +213,77 @@ func Test_history_addLast(t *testing.T) {
 	}
 }
 
+func Test_history_trim(t *testing.T) {
+	countTokens := func(msg Message) int { // 每条消息按 10 个 token 计算
+		return 10
+	}
+	entry := func(content string, answers ...Message) dialogEntry {
+		return dialogEntry{question: Message{Role: userRole, Content: content}, answerList: answers}
+	}
+	toolTurn := entry("查天气",
+		Message{Role: assistantRole, ToolCalls: []ToolCall{{ID: "call_1", Type: defaultFuncType, Function: FunctionCall{Name: "weather"}}}},
+		Message{Role: toolRole, ToolCallID: "call_1", Content: "晴"},
+		Message{Role: assistantRole, Content: "今天晴"},
+	) // 40 tokens
+	tests := []struct {
+		name        string
+		budget      int
+		wantEvicted int
+		wantFirst   string // 保留的最早问题
+	}{
+		{name: "fits", budget: 100, wantEvicted: 0, wantFirst: "你好"},
+		{name: "evict oldest", budget: 70, wantEvicted: 1, wantFirst: "查天气"},
+		{name: "tool turn is not split", budget: 50, wantEvicted: 2, wantFirst: "谢谢"},
+		{name: "only system", budget: 10, wantEvicted: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &history{system: Message{Role: systemRole, Content: "预设"}, countTokens: countTokens}
+			for _, e := range []dialogEntry{entry("你好", Message{Role: assistantRole, Content: "你好呀"}), toolTurn, entry("谢谢", Message{Role: assistantRole, Content: "不客气"})} {
+				h.addLast(e)
+			}
+			evicted := h.trim(tt.budget)
+			msgs := h.getMessage()
+			if len(evicted) != tt.wantEvicted || msgs[0].Role != systemRole {
+				t.Fatalf("trim() evicted = %d, messages = %+v", len(evicted), msgs)
+			}
+			if tt.wantFirst != "" && msgs[1].Content != tt.wantFirst {
+				t.Errorf("first question = %q, want %q", msgs[1].Content, tt.wantFirst)
+			}
+			for i, msg := range msgs { // tool 消息之前必须是对应的 tool_calls 回答
+				if msg.Role == toolRole && (i == 0 || len(msgs[i-1].ToolCalls) == 0 && msgs[i-1].Role != toolRole) {
+					t.Errorf("tool message %d split from its tool_calls: %+v", i, msgs)
+				}
+			}
+		})
+	}
+}
+
+func Test_tokenBudget(t *testing.T) {
+	tests := []struct {
+		model      string
+		configured int
+		params     config.ChatParams
+		want       int
+	}{
+		{model: "gpt-4o-mini", want: 128000 - defaultReplyReserve - replyTokenOverhead},
+		{model: "gpt-4-0613", params: config.ChatParams{MaxTokens: Ptr(500)}, want: 8192 - 500 - replyTokenOverhead},
+		{model: "gpt-4-32k", params: config.ChatParams{MaxTokens: Ptr(500), MaxCompletionTokens: Ptr(2000)}, want: 32768 - 2000 - replyTokenOverhead},
+		{model: "unknown-model", want: defaultContextWindow - defaultReplyReserve - replyTokenOverhead},
+		{model: "gpt-4o-mini", configured: 4096, want: 4096 - defaultReplyReserve - replyTokenOverhead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := tokenBudget(tt.model, tt.configured, tt.params); got != tt.want {
+				t.Errorf("tokenBudget() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+	if got := estimateMessageTokens(Message{Role: userRole, Content: "你好 world"}); got != messageTokenOverhead+1+2+2 {
+		t.Errorf("estimateMessageTokens() = %d", got)
+	}
+}
+
 var key *string
 
 func init() {
@@ -245,7 +318,9 @@ func TestFuncCall(t *testing.T) {
 		CallFunc: w,
 		//CustomTrigger: nil, // 暂时不测试
 	}
-	FuncRegister.Register(&funcCallInfo, []string{"天气", "weather"})
+	if err := FuncRegister.Register(&funcCallInfo, []string{"天气", "weather"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
 
 	type args struct {
 		sessionId string
@@ -290,3 +365,217 @@ func TestFuncCall(t *testing.T) {
 		})
 	}
 }
+
+type echoCallFunc struct{}
+
+func (echoCallFunc) Call(params string) (jsonStr string, err error) {
+	return `{"echo":` + params + `}`, nil
+}
+
+func Test_sessionInfo_TalkStream(t *testing.T) {
+	err := FuncRegister.Register(&FuncCallInfo{
+		Function: Function{
+			Name:        "stream_echo",
+			Description: "回显参数",
+			Parameters: FunctionParameter{
+				Type:       global.ObjType,
+				Properties: Properties{"text": Property{Type: global.StringType, Description: "文本"}},
+				Required:   []string{"text"},
+			},
+		},
+		CallFunc: echoCallFunc{},
+	}, []string{"stream_echo"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	server := newSSEServer(t, [][]string{
+		{
+			`{"id":"r1","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"stream_echo","arguments":""}}]}}]}`,
+			`{"id":"r1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"text\""}}]}}]}`,
+			`{"id":"r1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":":\"hi\"}"}}]},"finish_reason":"tool_calls"}]}`,
+			streamDoneFlag,
+		},
+		{
+			`{"id":"r2","choices":[{"index":0,"delta":{"role":"assistant","content":"回显"}}]}`,
+			`{"id":"r2","choices":[{"index":0,"delta":{"content":"：hi"},"finish_reason":"stop"}]}`,
+			streamDoneFlag,
+		},
+	}, func(round int, req ChatCompletionRequest) {
+		if round != 1 {
+			return
+		}
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role != toolRole || last.ToolCallID != "call_1" || last.Content != `{"echo":{"text":"hi"}}` {
+			t.Errorf("follow-up request last message = %+v, want tool reply", last)
+		}
+	})
+	defer server.Close()
+	old := aiclient
+	aiclient = newTestClient(server.URL)
+	defer func() { aiclient = old }()
+
+	s := NewSession("流式测试", 2)
+	var deltas []string
+	got, err := s.TalkStreamById("stream", "stream_echo hi", func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("TalkStreamById() error = %v", err)
+	}
+	if got != "回显：hi" || !reflect.DeepEqual(deltas, []string{"回显", "：hi"}) {
+		t.Errorf("TalkStreamById() got = %q, deltas = %q", got, deltas)
+	}
+	msgs := s.GetSession("stream", nil).history.getMessage()
+	if n := len(msgs); n != 5 || msgs[2].ToolCalls[0].Function.Arguments != `{"text":"hi"}` {
+		t.Errorf("history = %+v, want system, question, tool_calls, tool, answer", msgs)
+	}
+}
+
+// toolCallChoice 要求调用 loop_echo 的回答
+func toolCallChoice(id string, text string) Choice {
+	return Choice{
+		Message: Message{Role: assistantRole, ToolCalls: []ToolCall{{
+			ID: id, Type: defaultFuncType, Function: FunctionCall{Name: "loop_echo", Arguments: `{"text":"` + text + `"}`},
+		}}},
+		FinishReason: ToolsCallFinishReason,
+	}
+}
+
+func Test_sessionInfo_TalkToolLoop(t *testing.T) {
+	err := FuncRegister.Register(&FuncCallInfo{
+		Function: Function{
+			Name: "loop_echo",
+			Parameters: FunctionParameter{
+				Type:       global.ObjType,
+				Properties: Properties{"text": Property{Type: global.StringType}},
+				Required:   []string{"text"},
+			},
+		},
+		CallFunc: echoCallFunc{},
+	}, []string{"loop_echo"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err = FuncRegister.Register(&FuncCallInfo{
+		Function: Function{Name: "loop_sleep", Parameters: FunctionParameter{Type: global.ObjType}},
+		CallFunc: &testToolFunc{},
+	}, nil); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	final := Choice{Message: Message{Role: assistantRole, Content: "完成"}, FinishReason: "stop"}
+	sleepChoice := Choice{
+		Message: Message{Role: assistantRole, ToolCalls: []ToolCall{{
+			ID: "call_1", Type: defaultFuncType, Function: FunctionCall{Name: "loop_sleep", Arguments: "sleep"},
+		}}},
+		FinishReason: ToolsCallFinishReason,
+	}
+
+	tests := []struct {
+		name        string
+		maxRounds   int
+		maxDuration time.Duration
+		choices     []Choice
+		check       func(t *testing.T, round int, req ChatCompletionRequest)
+		want        string
+		wantErr     error
+		wantHistory int
+	}{
+		{
+			name:      "chained tools",
+			maxRounds: 5,
+			choices:   []Choice{toolCallChoice("call_1", "a"), toolCallChoice("call_2", "b"), final},
+			check: func(t *testing.T, round int, req ChatCompletionRequest) {
+				if req.Tools == nil || req.ToolChoice != "auto" {
+					t.Errorf("round %d tools = %v, tool_choice = %q", round, req.Tools, req.ToolChoice)
+				}
+				if round == 0 {
+					return
+				}
+				last := req.Messages[len(req.Messages)-1]
+				if wantID := fmt.Sprintf("call_%d", round); last.Role != toolRole || last.ToolCallID != wantID {
+					t.Errorf("round %d last message = %+v, want tool reply %s", round, last, wantID)
+				}
+				if n := len(req.Messages); n != 2+2*round {
+					t.Errorf("round %d messages = %d, want %d", round, n, 2+2*round)
+				}
+			},
+			want:        "完成",
+			wantHistory: 7, // system, question, (tool_calls, tool) * 2, answer
+		},
+		{
+			name:      "round budget",
+			maxRounds: 1,
+			choices:   []Choice{toolCallChoice("call_1", "a"), final},
+			check: func(t *testing.T, round int, req ChatCompletionRequest) {
+				if round == 1 && req.ToolChoice != toolChoiceNone {
+					t.Errorf("round %d tool_choice = %q, want none", round, req.ToolChoice)
+				}
+			},
+			want:        "完成",
+			wantHistory: 5,
+		},
+		{
+			name:        "slow tool exceeds time budget",
+			maxRounds:   5,
+			maxDuration: 100 * time.Millisecond,
+			choices:     []Choice{sleepChoice, final},
+			check: func(t *testing.T, round int, req ChatCompletionRequest) {
+				if round != 1 {
+					return
+				}
+				if last := req.Messages[len(req.Messages)-1]; req.ToolChoice != toolChoiceNone || !strings.Contains(last.Content, "error") {
+					t.Errorf("round %d tool_choice = %q, last message = %+v, want none and tool error", round, req.ToolChoice, last)
+				}
+			},
+			want:        "完成",
+			wantHistory: 5,
+		},
+		{
+			name:        "slow model exceeds time budget",
+			maxRounds:   5,
+			maxDuration: 100 * time.Millisecond,
+			choices:     []Choice{toolCallChoice("call_1", "a"), {Message: Message{Role: assistantRole, Content: "太慢"}, FinishReason: "stop"}, final},
+			check: func(t *testing.T, round int, req ChatCompletionRequest) {
+				if round == 1 && req.ToolChoice != toolChoiceNone {
+					time.Sleep(300 * time.Millisecond) // 超出耗时上限，请求被取消
+				}
+				if round == 2 && req.ToolChoice != toolChoiceNone {
+					t.Errorf("round %d tool_choice = %q, want none", round, req.ToolChoice)
+				}
+			},
+			want:        "完成",
+			wantHistory: 5,
+		},
+		{
+			name:        "budget exhausted",
+			maxRounds:   1,
+			choices:     []Choice{toolCallChoice("call_1", "a"), toolCallChoice("call_2", "b")},
+			wantErr:     toolLoopExhaustedErr,
+			wantHistory: 1, // 失败的对话不写入 history
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newChoiceServer(t, tt.choices, func(round int, req ChatCompletionRequest) {
+				if tt.check != nil {
+					tt.check(t, round, req)
+				}
+			})
+			defer server.Close()
+			old := aiclient
+			aiclient = newTestClient(server.URL)
+			defer func() { aiclient = old }()
+
+			s := NewSession("工具循环测试", 2)
+			s.SetToolLoopLimit(tt.maxRounds, tt.maxDuration)
+			got, err := s.TalkById("loop", "loop_echo")
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Fatalf("TalkById() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+			if msgs := s.GetSession("loop", nil).history.getMessage(); len(msgs) != tt.wantHistory {
+				t.Errorf("history = %+v, want %d messages", msgs, tt.wantHistory)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午6:00:00
+// @Desc 密钥的客户端限流：每分钟请求数 (RPM)、每分钟 token 数 (TPM) 使用令牌桶，另外限制同时进行的请求数
+// 选择密钥时跳过已达到限额的密钥，所有密钥均达到限额时等待 (不超过 rate_limit_wait)
+package ai_sdk
+
+import (
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/config"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+const concurrencyPoll = 20 * time.Millisecond // 并发数达到上限时重新检查的间隔
+
+// bucket 令牌桶，容量为每分钟的限额，按每秒 limit/60 的速度补充，limit 为 0 时不限制
+type bucket struct {
+	limit     float64
+	available float64
+	last      time.Time
+}
+
+func newBucket(perMinute int) bucket {
+	return bucket{limit: float64(perMinute), available: float64(perMinute)}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if !b.last.IsZero() && now.After(b.last) {
+		b.available = min(b.limit, b.available+now.Sub(b.last).Seconds()*b.limit/60)
+	}
+	b.last = now
+}
+
+// delay 令牌数达到 n 前需要等待的时间，n 超过容量时按容量计算
+func (b *bucket) delay(now time.Time, n float64) time.Duration {
+	if b.limit <= 0 {
+		return 0
+	}
+	b.refill(now)
+	n = min(n, b.limit)
+	if b.available >= n {
+		return 0
+	}
+	return time.Duration((n - b.available) / b.limit * 60 * float64(time.Second))
+}
+
+func (b *bucket) take(n float64) {
+	if b.limit > 0 {
+		b.available -= min(n, b.limit)
+	}
+}
+
+// refund 归还 (n 为负时扣除) 令牌，可能为负数，需等待补充后才能再次请求
+func (b *bucket) refund(n float64) {
+	if b.limit > 0 {
+		b.available = min(b.limit, b.available+n)
+	}
+}
+
+// rateLimiter 单个密钥的客户端限流
+type rateLimiter struct {
+	mu             sync.Mutex
+	requests       bucket
+	tokens         bucket
+	maxConcurrency int
+	active         int
+}
+
+// newRateLimiter 创建限流器，未设置任何限额时返回 nil
+func newRateLimiter(cfg config.RateLimitCfg) *rateLimiter {
+	if cfg.RPM <= 0 && cfg.TPM <= 0 && cfg.MaxConcurrency <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		requests:       newBucket(cfg.RPM),
+		tokens:         newBucket(cfg.TPM),
+		maxConcurrency: cfg.MaxConcurrency,
+	}
+}
+
+// delay 发起一次预估 tokens 个 token 的请求前需要等待的时间，0 表示可以立即请求
+func (l *rateLimiter) delay(now time.Time, tokens int) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.delayLocked(now, tokens)
+}
+
+func (l *rateLimiter) delayLocked(now time.Time, tokens int) time.Duration {
+	d := max(l.requests.delay(now, 1), l.tokens.delay(now, float64(tokens)))
+	if l.maxConcurrency > 0 && l.active >= l.maxConcurrency {
+		d = max(d, concurrencyPoll)
+	}
+	return d
+}
+
+// reserve 占用一次请求的额度，额度不足时返回 false
+func (l *rateLimiter) reserve(now time.Time, tokens int) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.delayLocked(now, tokens) > 0 {
+		return false
+	}
+	l.requests.take(1)
+	l.tokens.take(float64(tokens))
+	l.active++
+	return true
+}
+
+// release 请求结束，归还并发名额
+func (l *rateLimiter) release() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+}
+
+// settle 按接口返回的实际用量修正预估的 token 数
+func (l *rateLimiter) settle(estimated int, actual int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.refund(float64(estimated - actual))
+}
+
+// keyLease 一次请求占用的密钥额度，请求结束 (响应体关闭) 时归还并发名额
+type keyLease struct {
+	key     *keyState
+	tokens  int // 预估的 token 数
+	done    sync.Once
+	settled sync.Once
+}
+
+// acquire 占用密钥的额度，达到限额时返回 false；key 为 nil (构造后追加的密钥) 时不限制也不统计
+func (k *keyState) acquire(now time.Time, tokens int) (*keyLease, bool) {
+	if k == nil {
+		return nil, true
+	}
+	if !k.limit.reserve(now, tokens) {
+		return nil, false
+	}
+	k.load.inFlight.Add(1)
+	return &keyLease{key: k, tokens: tokens}, true
+}
+
+// release 请求结束，可重复调用
+func (l *keyLease) release() {
+	if l == nil {
+		return
+	}
+	l.done.Do(func() {
+		l.key.load.inFlight.Add(-1)
+		l.key.limit.release()
+	})
+}
+
+// observe 记录请求的响应延迟
+func (l *keyLease) observe(d time.Duration) {
+	if l != nil {
+		l.key.load.observe(d)
+	}
+}
+
+// settle 按实际用量修正预估的 token 数，仅第一次调用生效
+func (l *keyLease) settle(usage Usage) {
+	if l == nil || usage.TotalTokens <= 0 {
+		return
+	}
+	l.settled.Do(func() { l.key.limit.settle(l.tokens, usage.TotalTokens) })
+}
+
+// reportUsage 将响应中的 token 用量告知请求所用密钥的限流器
+func reportUsage(body io.ReadCloser, usage *Usage) {
+	if b, ok := body.(*cancelBody); ok && usage != nil {
+		b.lease.settle(*usage)
+	}
+}
+
+// keyRateLimit 密钥的限流设置：key_limits 中的设置优先，其次为 api 配置的 rate_limit
+func keyRateLimit(apiCfg config.APIConfig, auth string) config.RateLimitCfg {
+	for _, limit := range apiCfg.KeyLimits {
+		if limit.Auth == auth {
+			return limit.Limit
+		}
+	}
+	return apiCfg.RateLimit
+}
+
+// validateRateLimits 校验 api 配置的限流设置
+func validateRateLimits(apiCfg config.APIConfig) error {
+	limits := []config.RateLimitCfg{apiCfg.RateLimit}
+	for _, keyLimit := range apiCfg.KeyLimits {
+		if !slices.Contains(apiCfg.AuthList, keyLimit.Auth) {
+			return fmt.Errorf("key_limits: key %s not in authorization list", maskKey(keyLimit.Auth))
+		}
+		limits = append(limits, keyLimit.Limit)
+	}
+	for _, limit := range limits {
+		if limit.RPM < 0 || limit.TPM < 0 || limit.MaxConcurrency < 0 {
+			return fmt.Errorf("rate limit %+v must not be negative", limit)
+		}
+	}
+	return nil
+}
+
+// tpmLimited api 配置中是否有密钥设置了 TPM
+func tpmLimited(apiCfg config.APIConfig) bool {
+	for _, auth := range apiCfg.AuthList {
+		if keyRateLimit(apiCfg, auth).TPM > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// estimateRequestTokens 预估请求计入 TPM 的 token 数：提示词 token 数 + 最大生成 token 数
+func estimateRequestTokens(request ChatCompletionRequest) int {
+	countMessage := messageTokenCounter(request.Model)
+	n := replyTokenOverhead + toolsTokenCounter(request.Model)(request.Tools)
+	for _, msg := range request.Messages {
+		n += countMessage(msg)
+	}
+	switch {
+	case request.MaxCompletionTokens != nil:
+		n += *request.MaxCompletionTokens
+	case request.MaxTokens != nil:
+		n += *request.MaxTokens
+	}
+	return n
+}
+
+// ready 未达到限额的候选，均达到限额时返回需要等待的最短时间
+func (a AIClient) ready(candidates []Candidate, tokens int, now time.Time) ([]Candidate, time.Duration) {
+	ready := candidates[:0:0]
+	var wait time.Duration
+	for _, c := range candidates {
+		d := a.endpointAt(c.Endpoint).key(c.Key).rateDelay(now, tokens)
+		if d == 0 {
+			ready = append(ready, c)
+		} else if wait == 0 || d < wait {
+			wait = d
+		}
+	}
+	return ready, wait
+}
+
+func (k *keyState) rateDelay(now time.Time, tokens int) time.Duration {
+	if k == nil {
+		return 0
+	}
+	return k.limit.delay(now, tokens)
+}
+
+// rateLimitWaitFromConfig 配置中的 rate_limit_wait 转换为等待时间，0 使用默认值，小于 0 表示不等待
+func rateLimitWaitFromConfig(seconds int) time.Duration {
+	switch {
+	case seconds == 0:
+		return config.DefaultRateLimitWait * time.Second
+	case seconds < 0:
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,164 @@
+package ai_sdk
+
+import (
+	"errors"
+	"github.com/Clov614/go-ai-sdk/config"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	start := time.Now()
+	t.Run("rpm", func(t *testing.T) {
+		l := newRateLimiter(config.RateLimitCfg{RPM: 2})
+		for i := 0; i < 2; i++ {
+			if !l.reserve(start, 0) {
+				t.Fatalf("reserve #%d rejected", i)
+			}
+			l.release()
+		}
+		if l.reserve(start, 0) {
+			t.Fatalf("reserve beyond rpm accepted")
+		}
+		if d := l.delay(start, 0); d != 30*time.Second {
+			t.Errorf("delay() = %s, want 30s", d)
+		}
+		if !l.reserve(start.Add(30*time.Second), 0) {
+			t.Errorf("reserve after refill rejected")
+		}
+	})
+	t.Run("tpm with usage settlement", func(t *testing.T) {
+		l := newRateLimiter(config.RateLimitCfg{TPM: 1000})
+		if !l.reserve(start, 600) {
+			t.Fatalf("first reserve rejected")
+		}
+		if l.reserve(start, 600) {
+			t.Fatalf("reserve beyond tpm accepted")
+		}
+		l.settle(600, 100) // 实际只使用了 100 个 token
+		if !l.reserve(start, 600) {
+			t.Errorf("reserve after settlement rejected")
+		}
+		if !newRateLimiter(config.RateLimitCfg{TPM: 10}).reserve(start, 100) {
+			t.Errorf("request larger than tpm never admitted")
+		}
+	})
+	t.Run("concurrency", func(t *testing.T) {
+		l := newRateLimiter(config.RateLimitCfg{MaxConcurrency: 1})
+		if !l.reserve(start, 0) || l.reserve(start, 0) {
+			t.Fatalf("max concurrency not enforced")
+		}
+		l.release()
+		if !l.reserve(start, 0) {
+			t.Errorf("reserve after release rejected")
+		}
+	})
+	if newRateLimiter(config.RateLimitCfg{}) != nil {
+		t.Errorf("limiter without limits should be nil")
+	}
+}
+
+func TestAIClient_RateLimit(t *testing.T) {
+	server, auths := newScriptedServer(t, nil)
+	apiCfg := config.APIConfig{
+		Url:       server.URL,
+		AuthList:  []string{"sk-a", "sk-b"},
+		RateLimit: config.RateLimitCfg{RPM: 1},
+		KeyLimits: []config.KeyLimit{{Auth: "sk-b", Limit: config.RateLimitCfg{RPM: 2}}},
+	}
+	a, err := NewClient([]config.APIConfig{apiCfg}, WithRateLimitWait(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err = a.Send(Request{Messages: []Message{{Role: userRole, Content: "hi"}}}); err != nil {
+			t.Fatalf("Send() #%d error = %v", i, err)
+		}
+	}
+	// sk-a 达到限额后跳过，由 sk-b 处理
+	want := []string{"Bearer sk-a", "Bearer sk-b", "Bearer sk-b"}
+	if got := auths(); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("requests = %v, want %v", got, want)
+	}
+	begin := time.Now()
+	_, err = a.Send(Request{Messages: []Message{{Role: userRole, Content: "hi"}}})
+	if !errors.Is(err, localRateLimitErr) {
+		t.Errorf("Send() error = %v, want localRateLimitErr", err)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("Send() waited %s beyond rate limit wait", elapsed)
+	}
+
+	if _, err = NewClient([]config.APIConfig{{Url: server.URL, AuthList: []string{"sk-a"},
+		KeyLimits: []config.KeyLimit{{Auth: "sk-unknown"}}}}); !errors.Is(err, clientOptionErr) {
+		t.Errorf("NewClient() with unknown key limit error = %v, want clientOptionErr", err)
+	}
+}
+
+func TestAIClient_ConcurrencyWait(t *testing.T) {
+	server := newSSEServer(t, [][]string{
+		{`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"hi"}}]}`, streamDoneFlag},
+		{`{"id":"c2","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"hi"}}]}`, streamDoneFlag},
+	}, nil)
+	defer server.Close()
+	a, err := NewClient([]config.APIConfig{{Url: server.URL, AuthList: []string{"sk-a"},
+		RateLimit: config.RateLimitCfg{MaxConcurrency: 1}}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	stream, err := a.SendStream(Request{Messages: []Message{{Role: userRole, Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("SendStream() error = %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		s, err := a.SendStream(Request{Messages: []Message{{Role: userRole, Content: "hi"}}})
+		if err == nil {
+			s.Close()
+		}
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		t.Fatalf("second stream started while the first one is open: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	stream.Close() // 归还并发名额
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Errorf("second SendStream() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("second stream still waiting after the first one closed")
+	}
+}
+
+func TestAIClient_TokenLimitSettlement(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-test","choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":8,"completion_tokens":2,"total_tokens":10}}`))
+	}))
+	defer server.Close()
+	a, err := NewClient([]config.APIConfig{{Url: server.URL, AuthList: []string{"sk-a"},
+		RateLimit: config.RateLimitCfg{TPM: 10000}}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	maxTokens := 500
+	req := Request{Messages: []Message{{Role: userRole, Content: "hi"}}}
+	req.MaxTokens = &maxTokens
+	if _, err = a.Send(req); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	limiter := a.endpoints[0].keys[0].limit
+	limiter.mu.Lock()
+	available := limiter.tokens.available
+	limiter.mu.Unlock()
+	// 预估的 500+ 个 token 在响应后按实际用量 10 修正 (允许补充带来的少量误差)
+	if math.Abs(available-(10000-10)) > 1 {
+		t.Errorf("available tokens = %.1f, want about %d", available, 10000-10)
+	}
+}
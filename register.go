@@ -5,9 +5,11 @@
 package ai_sdk
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
@@ -23,8 +25,14 @@ type FuncCallRegister struct {
 	mu                  sync.RWMutex
 }
 
-// Register 注册调用方法
-func (fc *FuncCallRegister) Register(finfo *FuncCallInfo, triggerWords []string) {
+// Register 注册调用方法，方法定义 (名称与参数 schema) 不合法时拒绝注册并返回错误
+func (fc *FuncCallRegister) Register(finfo *FuncCallInfo, triggerWords []string) error {
+	if finfo == nil || finfo.CallFunc == nil {
+		return fmt.Errorf("%w: CallFunc is nil", funcRegisterErr)
+	}
+	if err := finfo.Function.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", funcRegisterErr, err)
+	}
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
 	fc.Name2Info[finfo.Name] = finfo
@@ -38,6 +46,7 @@ func (fc *FuncCallRegister) Register(finfo *FuncCallInfo, triggerWords []string)
 	if finfo.CustomTrigger != nil { // 自定义触发器
 		fc.filter = append(fc.filter, finfo)
 	}
+	return nil
 }
 
 // GetCallInfo 根据方法名获取方法调用信息
@@ -125,10 +134,16 @@ type CallFunc interface {
 	Call(params string) (jsonStr string, err error) // 调用外部函数，返回json
 }
 
+// CallFuncWithContext 支持 context 的外部函数，实现该接口的 CallFunc 会优先以此方式调用
+type CallFuncWithContext interface {
+	CallWithContext(ctx context.Context, params string) (jsonStr string, err error)
+}
+
 type FuncCallInfo struct {
 	Function      // 方法信息
 	CallFunc      // 外部函数
 	CustomTrigger func(content string) bool
+	Timeout       time.Duration // 单次调用超时时间 (可选)，优先于会话与配置中的 tool_timeout
 }
 
 func (fc *FuncCallInfo) IsCall(content string) bool {
@@ -140,7 +155,22 @@ func (fc *FuncCallInfo) IsCall(content string) bool {
 
 // Call 调用方法
 func (fc *FuncCallInfo) Call(callId string, params string) (Message, error) {
-	content, err := fc.CallFunc.Call(params)
+	return fc.CallWithContext(context.Background(), callId, params)
+}
+
+// CallWithContext 携带 context 调用方法
+// 未实现 CallFuncWithContext 的外部函数无法被中断，ctx 结束时直接返回错误，不再等待其结果
+func (fc *FuncCallInfo) CallWithContext(ctx context.Context, callId string, params string) (Message, error) {
+	if err := ctx.Err(); err != nil {
+		return Message{}, fmt.Errorf("FuncCallInfo.CallWithContext canceled: %w", err)
+	}
+	var content string
+	var err error
+	if ctxCall, ok := fc.CallFunc.(CallFuncWithContext); ok {
+		content, err = ctxCall.CallWithContext(ctx, params)
+	} else {
+		content, err = callUntilDone(ctx, fc.CallFunc, params)
+	}
 	if err != nil {
 		return Message{}, fmt.Errorf("FuncCallInfo.CallFunc.Call error: %w", err)
 	}
@@ -151,6 +181,30 @@ func (fc *FuncCallInfo) Call(callId string, params string) (Message, error) {
 	}, nil
 }
 
+// callUntilDone 在独立 goroutine 中执行不支持 context 的外部函数，ctx 结束时提前返回
+func callUntilDone(ctx context.Context, callFunc CallFunc, params string) (string, error) {
+	type result struct {
+		content string
+		err     error
+	}
+	ch := make(chan result, 1) // 带缓冲，提前返回后 goroutine 仍可写入并退出
+	go func() {
+		defer func() {
+			if r := recover(); r != nil { // 外部函数 panic 时转为错误，避免导致进程退出
+				ch <- result{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		content, err := callFunc.Call(params)
+		ch <- result{content: content, err: err}
+	}()
+	select {
+	case r := <-ch:
+		return r.content, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
 func init() {
 	FuncRegister = FuncCallRegister{
 		Name2Info:           make(map[string]*FuncCallInfo),
@@ -0,0 +1,110 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午5:02:00
+// @Desc 类型化方法调用注册：参数 schema、解码、校验与结果编码由注册器统一处理
+package ai_sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"strings"
+)
+
+// TypedHandler 类型化的外部函数，Args 为参数结构体，Result 会被编码为 JSON 返回给模型 (string 类型原样返回)
+type TypedHandler[Args any, Result any] func(ctx context.Context, args Args) (Result, error)
+
+// NewTypedFuncCallInfo 根据类型化处理函数生成方法调用信息，参数 schema 由 Args 生成
+func NewTypedFuncCallInfo[Args any, Result any](name string, description string, handler TypedHandler[Args, Result]) (*FuncCallInfo, error) {
+	function, err := NewFunction[Args](name, description, false)
+	if err != nil {
+		return nil, fmt.Errorf("NewTypedFuncCallInfo: %w", err)
+	}
+	return &FuncCallInfo{
+		Function: function,
+		CallFunc: &typedCallFunc[Args, Result]{
+			name:    name,
+			schema:  function.Parameters.property(),
+			handler: handler,
+		},
+	}, nil
+}
+
+// RegisterTyped 注册类型化处理函数，triggerWords 为触发关键词
+func RegisterTyped[Args any, Result any](fc *FuncCallRegister, name string, description string, handler TypedHandler[Args, Result], triggerWords []string) error {
+	finfo, err := NewTypedFuncCallInfo(name, description, handler)
+	if err != nil {
+		return err
+	}
+	return fc.Register(finfo, triggerWords)
+}
+
+// typedCallFunc 将 TypedHandler 适配为 CallFunc
+type typedCallFunc[Args any, Result any] struct {
+	name    string
+	schema  Property
+	handler TypedHandler[Args, Result]
+}
+
+func (f *typedCallFunc[Args, Result]) Call(params string) (jsonStr string, err error) {
+	return f.CallWithContext(context.Background(), params)
+}
+
+// CallWithContext 校验并解码参数后调用处理函数
+// 参数错误与处理函数返回的错误以 {"error": "..."} 的形式返回给模型，便于模型修正参数或告知用户；仅 ctx 结束时返回 error
+func (f *typedCallFunc[Args, Result]) CallWithContext(ctx context.Context, params string) (jsonStr string, err error) {
+	if strings.TrimSpace(params) == "" {
+		params = "{}"
+	}
+	if err = validateJSON(f.schema, []byte(params)); err != nil {
+		return f.errorResult("invalid arguments", err), nil
+	}
+	var args Args
+	if err = json.Unmarshal([]byte(params), &args); err != nil {
+		return f.errorResult("invalid arguments", err), nil
+	}
+	result, err := f.handler(ctx, args)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("function %s canceled: %w", f.name, ctx.Err())
+		}
+		return f.errorResult("call failed", err), nil
+	}
+	if s, ok := interface{}(result).(string); ok {
+		return s, nil
+	}
+	content, err := marshalToolContent(result)
+	if err != nil {
+		return f.errorResult("encode result failed", err), nil
+	}
+	return content, nil
+}
+
+// toolErrorResult 返回给模型的错误信息
+type toolErrorResult struct {
+	Error string `json:"error"`
+}
+
+func (f *typedCallFunc[Args, Result]) errorResult(reason string, err error) string {
+	return toolErrorContent(f.name, reason, err)
+}
+
+// toolErrorContent 记录日志并生成返回给模型的错误内容
+func toolErrorContent(name string, reason string, err error) string {
+	log.Warn().Err(err).Str("function", name).Msg(reason)
+	content, _ := marshalToolContent(toolErrorResult{Error: fmt.Sprintf("%s %s: %v", name, reason, err)})
+	return content
+}
+
+// marshalToolContent 编码返回给模型的内容，不转义 HTML 字符以保持可读
+func marshalToolContent(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,71 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午5:30:00
+// @Desc 类型化方法调用注册测试
+package ai_sdk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testAddArgs struct {
+	A int `json:"a" jsonschema:"description=加数,minimum=0"`
+	B int `json:"b" jsonschema:"description=加数"`
+}
+
+type testAddResult struct {
+	Sum int `json:"sum"`
+}
+
+func TestRegisterTyped(t *testing.T) {
+	fc := &FuncCallRegister{
+		Name2Info:           make(map[string]*FuncCallInfo),
+		keyWord2FuncNameMap: make(map[string]funcInfoList),
+		filter:              make(filter, 0),
+	}
+	err := RegisterTyped(fc, "typed_add", "两数相加", func(ctx context.Context, args testAddArgs) (testAddResult, error) {
+		if args.B == 13 {
+			return testAddResult{}, errors.New("unlucky number")
+		}
+		return testAddResult{Sum: args.A + args.B}, nil
+	}, []string{"相加"})
+	if err != nil {
+		t.Fatalf("RegisterTyped() error = %v", err)
+	}
+	callInfo := fc.GetCallInfo("typed_add")
+	if callInfo == nil || len(callInfo.Parameters.Required) != 2 {
+		t.Fatalf("GetCallInfo() = %+v", callInfo)
+	}
+
+	tests := []struct {
+		name        string
+		params      string
+		wantContent string
+	}{
+		{name: "ok", params: `{"a":1,"b":2}`, wantContent: `{"sum":3}`},
+		{name: "missing argument", params: `{"a":1}`, wantContent: `{"error":"typed_add invalid arguments: schema validation failed: $.b is required"}`},
+		{name: "out of range", params: `{"a":-1,"b":2}`, wantContent: `{"error":"typed_add invalid arguments: schema validation failed: $.a must be >= 0"}`},
+		{name: "malformed json", params: `{"a":1,`, wantContent: `{"error":"typed_add invalid arguments: schema validation failed: invalid json`},
+		{name: "handler error", params: `{"a":1,"b":13}`, wantContent: `{"error":"typed_add call failed: unlucky number"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := callInfo.Call("call_1", tt.params)
+			if err != nil {
+				t.Fatalf("Call() error = %v", err)
+			}
+			if msg.Role != toolRole || msg.ToolCallID != "call_1" || !strings.HasPrefix(msg.Content, tt.wantContent) {
+				t.Errorf("Call() = %+v, want content %s", msg, tt.wantContent)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = callInfo.CallWithContext(ctx, "call_2", `{"a":1,"b":2}`); !errors.Is(err, context.Canceled) {
+		t.Errorf("CallWithContext() canceled error = %v, want context.Canceled", err)
+	}
+}
@@ -4,17 +4,31 @@
 // @Desc gpt实体类 request
 package ai_sdk
 
+import "github.com/Clov614/go-ai-sdk/config"
+
 type Request struct {
-	Messages   []Message
-	Tools      *[]Tool
-	ToolChoice string
+	Model             string // 本次请求使用的模型，为空时使用 AIClient.Model
+	Messages          []Message
+	Tools             *[]Tool
+	ToolChoice        string
+	StreamOptions     *StreamOptions    // 仅流式请求生效
+	config.ChatParams                   // 本次请求的生成参数，优先级最高
+	defaults          config.ChatParams // 会话默认生成参数
 }
 
 type ChatCompletionRequest struct {
-	Model      string    `json:"model"`
-	Messages   []Message `json:"messages"`
-	Tools      *[]Tool   `json:"tools,omitempty"`       // 可选
-	ToolChoice string    `json:"tool_choice,omitempty"` // 默认 auto
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	Tools         *[]Tool        `json:"tools,omitempty"`       // 可选
+	ToolChoice    string         `json:"tool_choice,omitempty"` // 默认 auto
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"` // 仅 stream 为 true 时可设置
+	config.ChatParams
+}
+
+// Ptr 返回 v 的指针，便于设置 config.ChatParams 中的可选参数
+func Ptr[T any](v T) *T {
+	return &v
 }
 
 const (
@@ -24,6 +38,7 @@ const (
 type Message struct {
 	Role       string     `json:"role"`
 	Content    string     `json:"content,omitempty"`      // Content可能为null
+	Refusal    string     `json:"refusal,omitempty"`      // 结构化输出时模型拒绝回答的原因
 	ToolCallID string     `json:"tool_call_id,omitempty"` // 用于关联工具调用
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 }
@@ -32,18 +47,33 @@ type Message struct {
 
 // FunctionParameter 定义函数参数类型
 type FunctionParameter struct {
-	Type       string `json:"type"`
-	Properties `json:"properties"`
-	Required   []string `json:"required"`
+	Type                 string `json:"type"`
+	Properties           `json:"properties"`
+	Required             []string    `json:"required"`
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"` // strict 模式需为 false
 }
 
 type Properties map[string]Property
 
-// Property 定义函数属性类型
+// Property 定义函数属性类型 (JSON Schema)
 type Property struct {
-	Type        string   `json:"type"`
-	Description string   `json:"description"`
-	Enum        []string `json:"enum,omitempty"` // 用于枚举类型的字段
+	Type                 string      `json:"type,omitempty"`
+	Description          string      `json:"description,omitempty"`
+	Enum                 []string    `json:"enum,omitempty"`                 // 枚举值，integer/number/boolean 类型序列化时按 Type 转换
+	Items                *Property   `json:"items,omitempty"`                // array 元素类型
+	Properties           Properties  `json:"properties,omitempty"`           // object 属性
+	Required             []string    `json:"required,omitempty"`             // object 必填属性
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"` // object 额外属性: bool 或 Property
+	Minimum              *float64    `json:"minimum,omitempty"`              // number/integer 最小值
+	Maximum              *float64    `json:"maximum,omitempty"`              // number/integer 最大值
+	MinLength            *int        `json:"minLength,omitempty"`            // string 最小长度
+	MaxLength            *int        `json:"maxLength,omitempty"`            // string 最大长度
+	MinItems             *int        `json:"minItems,omitempty"`             // array 最少元素数
+	MaxItems             *int        `json:"maxItems,omitempty"`             // array 最多元素数
+	Pattern              string      `json:"pattern,omitempty"`              // string 正则约束 (按 Go RE2 语法校验)
+	Default              interface{} `json:"default,omitempty"`              // 默认值
+	AnyOf                []Property  `json:"anyOf,omitempty"`                // 满足任意一个子 schema
+	OneOf                []Property  `json:"oneOf,omitempty"`                // 恰好满足一个子 schema
 }
 
 // Tool 定义函数类型的工具
@@ -62,6 +92,7 @@ type Function struct {
 
 //endregion
 
+// StreamOptions 流式请求选项
 type StreamOptions struct {
-	IncludeUsage bool `json:"include_usage,omitempty"`
+	IncludeUsage bool `json:"include_usage,omitempty"` // 结束前额外推送一条携带 usage 的数据块
 }
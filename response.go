@@ -4,6 +4,12 @@
 // @Desc
 package ai_sdk
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
 type Response[T any | DefalutResponse | FunctionCallResponse] struct {
 	ID       string `json:"id"`
 	Object   string `json:"object"`
@@ -29,8 +35,34 @@ type FunctionCallResponse struct {
 }
 
 type Delta struct {
-	Role    string `json:"role,omitempty"`
-	Content string `json:"content,omitempty"`
+	Role      string          `json:"role,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	ToolCalls []ToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// ToolCallDelta 流式响应中的工具调用片段，同一 Index 的片段需按顺序拼接
+type ToolCallDelta struct {
+	Index    int          `json:"index"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+// ChatCompletionStreamResponse 流式响应数据块 (SSE data)
+type ChatCompletionStreamResponse struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []StreamChoice `json:"choices"`
+	Usage   *Usage         `json:"usage,omitempty"` // 仅 include_usage 时的最后一个数据块携带
+}
+
+type StreamChoice struct {
+	Index        int         `json:"index"`
+	Delta        Delta       `json:"delta"`
+	Logprobs     interface{} `json:"logprobs,omitempty"`
+	FinishReason string      `json:"finish_reason"`
 }
 
 type Choice struct {
@@ -61,5 +93,56 @@ type RespError struct {
 	Message string `json:"message"`
 	Type    string `json:"type"`
 	Param   string `json:"param"`
-	Code    int    `json:"code"`
+	Code    int    `json:"code"` // 数字错误码 (部分中转返回)，错误码为字符串时为 0
+	RawCode string `json:"-"`    // 原始错误码，OpenAI 为字符串 (如 "insufficient_quota")，数字错误码同样以字符串保存
+}
+
+// UnmarshalJSON 兼容字符串与数字两种错误码
+func (e *RespError) UnmarshalJSON(data []byte) error {
+	type respError RespError
+	var v struct {
+		*respError
+		Code json.RawMessage `json:"code"`
+	}
+	v.respError = (*respError)(e)
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	e.Code, e.RawCode = 0, ""
+	if len(v.Code) == 0 || string(v.Code) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(v.Code, &e.RawCode); err == nil {
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(v.Code, &n); err != nil {
+		return fmt.Errorf("error code %s: %w", v.Code, err)
+	}
+	e.RawCode = n.String()
+	if code, err := strconv.Atoi(e.RawCode); err == nil {
+		e.Code = code
+	}
+	return nil
+}
+
+// errorResponse 接口返回的错误 {"error": {...}}
+type errorResponse struct {
+	Error RespError `json:"error"`
+}
+
+// parseRespError 解析错误响应体，兼容直接返回错误对象的中转
+func parseRespError(body []byte) RespError {
+	var errResp errorResponse
+	if json.Unmarshal(body, &errResp) == nil && (errResp.Error.Message != "" || errResp.Error.RawCode != "") {
+		return errResp.Error
+	}
+	var respErr RespError
+	_ = json.Unmarshal(body, &respErr)
+	return respErr
+}
+
+// Err 响应中携带的接口错误信息
+func (r Response[T]) Err() RespError {
+	return r.err
 }
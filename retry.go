@@ -0,0 +1,170 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午5:00:00
+// @Desc 请求重试策略：同一密钥上对 429、5xx、连接重置与超时按指数退避 (含随机抖动) 重试，优先遵循 Retry-After 与 x-ratelimit-reset-* 响应头
+package ai_sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/config"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy 单个密钥的重试策略，重试次数用尽或遇到不可重试的错误 (400、401、404 等) 后切换下一个密钥
+type RetryPolicy struct {
+	MaxAttempts int           // 单个密钥最多请求次数 (含首次)，小于 1 时按 1 处理 (不重试)
+	BaseDelay   time.Duration // 首次重试的退避时间，之后每次翻倍，实际等待时间在 [d/2, d] 之间随机
+	MaxDelay    time.Duration // 单次等待的最长时间，接口要求的等待时间超过该值时直接切换下一个密钥，0 表示不限制
+}
+
+// DefaultRetryPolicy 默认重试策略：单个密钥最多请求 3 次，退避时间 500ms 起，单次最长等待 20s
+func DefaultRetryPolicy() RetryPolicy {
+	return retryPolicyFromConfig(config.RetryCfg{})
+}
+
+// retryPolicyFromConfig 根据配置创建重试策略，未设置的配置项使用默认值
+func retryPolicyFromConfig(cfg config.RetryCfg) RetryPolicy {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = config.DefaultRetryAttempts
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = config.DefaultRetryBaseDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = config.DefaultRetryMaxDelay
+	}
+	return RetryPolicy{
+		MaxAttempts: cfg.MaxAttempts,
+		BaseDelay:   time.Duration(cfg.BaseDelay) * time.Millisecond,
+		MaxDelay:    time.Duration(cfg.MaxDelay) * time.Millisecond,
+	}
+}
+
+func (p RetryPolicy) validate() error {
+	if p.BaseDelay < 0 || p.MaxDelay < 0 {
+		return fmt.Errorf("%w: negative retry delay", clientOptionErr)
+	}
+	return nil
+}
+
+// attempts 单个密钥的请求次数
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff 第 attempt 次请求失败后的退避时间 (attempt 从 1 开始)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1)) // nolint:gosec 抖动无需安全随机数
+}
+
+// wait 第 attempt 次请求失败后的等待时间，接口要求的等待时间 retryAfter 超过 MaxDelay 时返回 false
+func (p RetryPolicy) wait(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	if retryAfter <= 0 {
+		return p.backoff(attempt), true
+	}
+	if p.MaxDelay > 0 && retryAfter > p.MaxDelay {
+		return retryAfter, false
+	}
+	return retryAfter, true
+}
+
+// retryableStatus 可在同一密钥上重试的状态码
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryableError 可重试的网络错误：连接被重置、连接被提前关闭、超时
+func retryableError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryAfter 解析接口要求的等待时间：Retry-After-Ms > Retry-After (秒或 HTTP 日期) > 已耗尽 (x-ratelimit-remaining-* 为 0) 的限额对应的 x-ratelimit-reset-*
+// 请求数与 token 限额均耗尽时取较长者；未携带、无法解析或没有耗尽的限额时返回 0
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if v := header.Get("Retry-After-Ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	if v := header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			if seconds > 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		} else if at, err := http.ParseTime(v); err == nil && at.After(now) {
+			return at.Sub(now)
+		}
+	}
+	var d time.Duration
+	for _, limit := range []string{"Requests", "Tokens"} {
+		if strings.TrimSpace(header.Get("X-Ratelimit-Remaining-"+limit)) != "0" { // 未耗尽的限额不需要等待其重置
+			continue
+		}
+		if reset := parseResetDuration(header.Get("X-Ratelimit-Reset-" + limit)); reset > d {
+			d = reset
+		}
+	}
+	return d
+}
+
+// parseResetDuration 解析 x-ratelimit-reset-* 的值，格式如 "1s"、"6m0s"、"20ms"，无单位时按秒处理
+func parseResetDuration(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return d
+	}
+	if seconds, err := strconv.ParseFloat(v, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return 0
+}
+
+// sleepContext 等待 d，ctx 取消时提前返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
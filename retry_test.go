@@ -0,0 +1,180 @@
+package ai_sdk
+
+import (
+	"errors"
+	"github.com/Clov614/go-ai-sdk/config"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedReply 测试服务的一次响应，status 为 0 时直接断开连接
+type scriptedReply struct {
+	status int
+	header map[string]string
+}
+
+// newScriptedServer 第 n 次请求按 replies[n] 响应 (超出时返回 200)，记录每次请求使用的密钥
+func newScriptedServer(t *testing.T, replies []scriptedReply) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n := len(auths)
+		auths = append(auths, r.Header.Get("Authorization"))
+		mu.Unlock()
+		if n >= len(replies) {
+			_, _ = w.Write([]byte(okBody))
+			return
+		}
+		reply := replies[n]
+		if reply.status == 0 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		for k, v := range reply.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(reply.status)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), auths...)
+	}
+}
+
+func TestAIClient_Retry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	tests := []struct {
+		name      string
+		replies   []scriptedReply
+		wantAuths []string
+		wantErr   error
+	}{
+		{
+			name:      "429 with retry-after then ok",
+			replies:   []scriptedReply{{status: 429, header: map[string]string{"Retry-After-Ms": "5"}}},
+			wantAuths: []string{"Bearer sk-1", "Bearer sk-1"},
+		},
+		{
+			name:      "5xx retried on same key",
+			replies:   []scriptedReply{{status: 503}, {status: 502}},
+			wantAuths: []string{"Bearer sk-1", "Bearer sk-1", "Bearer sk-1"},
+		},
+		{
+			name:      "connection reset retried",
+			replies:   []scriptedReply{{status: 0}},
+			wantAuths: []string{"Bearer sk-1", "Bearer sk-1"},
+		},
+		{
+			name:      "attempts exhausted then failover",
+			replies:   []scriptedReply{{status: 500}, {status: 500}, {status: 500}},
+			wantAuths: []string{"Bearer sk-1", "Bearer sk-1", "Bearer sk-1", "Bearer sk-2"},
+		},
+		{
+			name:      "fatal status not retried",
+			replies:   []scriptedReply{{status: 400}, {status: 401}},
+			wantAuths: []string{"Bearer sk-1", "Bearer sk-2"},
+			wantErr:   unAuthErr,
+		},
+		{
+			name:      "retry-after beyond max delay fails over",
+			replies:   []scriptedReply{{status: 429, header: map[string]string{"Retry-After": "60"}}},
+			wantAuths: []string{"Bearer sk-1", "Bearer sk-2"},
+		},
+		{
+			name: "rate limited on every key",
+			replies: []scriptedReply{{status: 429}, {status: 429}, {status: 429},
+				{status: 429}, {status: 429}, {status: 429}},
+			wantAuths: []string{"Bearer sk-1", "Bearer sk-1", "Bearer sk-1", "Bearer sk-2", "Bearer sk-2", "Bearer sk-2"},
+			wantErr:   rateLimitErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, auths := newScriptedServer(t, tt.replies)
+			a, err := NewClient([]config.APIConfig{{Url: server.URL, AuthList: []string{"sk-1", "sk-2"}}},
+				WithRetryPolicy(policy))
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			_, err = a.Send(Request{Messages: []Message{{Role: userRole, Content: "hi"}}})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Send() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("Send() error = %v", err)
+			}
+			got := auths()
+			if len(got) != len(tt.wantAuths) {
+				t.Fatalf("requests = %v, want %v", got, tt.wantAuths)
+			}
+			for i := range got {
+				if got[i] != tt.wantAuths[i] {
+					t.Errorf("request %d auth = %s, want %s", i, got[i], tt.wantAuths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 19, 5, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   time.Duration
+	}{
+		{name: "none", want: 0},
+		{name: "seconds", header: map[string]string{"Retry-After": "3"}, want: 3 * time.Second},
+		{name: "http date", header: map[string]string{"Retry-After": now.Add(2 * time.Second).Format(http.TimeFormat)}, want: 2 * time.Second},
+		{name: "milliseconds first", header: map[string]string{"Retry-After-Ms": "250", "Retry-After": "3"}, want: 250 * time.Millisecond},
+		{name: "requests exhausted", header: map[string]string{"X-Ratelimit-Remaining-Requests": "0", "X-Ratelimit-Reset-Requests": "120ms",
+			"X-Ratelimit-Remaining-Tokens": "5000", "X-Ratelimit-Reset-Tokens": "6m0s"}, want: 120 * time.Millisecond},
+		{name: "tokens exhausted", header: map[string]string{"X-Ratelimit-Remaining-Requests": "59", "X-Ratelimit-Reset-Requests": "120ms",
+			"X-Ratelimit-Remaining-Tokens": "0", "X-Ratelimit-Reset-Tokens": "6m0s"}, want: 6 * time.Minute},
+		{name: "both exhausted", header: map[string]string{"X-Ratelimit-Remaining-Requests": "0", "X-Ratelimit-Reset-Requests": "120ms",
+			"X-Ratelimit-Remaining-Tokens": "0", "X-Ratelimit-Reset-Tokens": "6m0s"}, want: 6 * time.Minute},
+		{name: "ratelimit reset seconds", header: map[string]string{"X-Ratelimit-Remaining-Requests": "0", "X-Ratelimit-Reset-Requests": "1.5"}, want: 1500 * time.Millisecond},
+		{name: "nothing exhausted", header: map[string]string{"X-Ratelimit-Remaining-Requests": "3", "X-Ratelimit-Reset-Requests": "1s"}, want: 0},
+		{name: "remaining missing", header: map[string]string{"X-Ratelimit-Reset-Tokens": "6m0s"}, want: 0},
+		{name: "invalid", header: map[string]string{"Retry-After": "soon"}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.header {
+				header.Set(k, v)
+			}
+			if got := retryAfter(header, now); got != tt.want {
+				t.Errorf("retryAfter() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 5: time.Second} {
+		for i := 0; i < 20; i++ {
+			if got := p.backoff(attempt); got < want/2 || got > want {
+				t.Fatalf("backoff(%d) = %s, want in [%s, %s]", attempt, got, want/2, want)
+			}
+		}
+	}
+	if _, ok := p.wait(1, 2*time.Second); ok {
+		t.Errorf("wait() accepted retry-after beyond max delay")
+	}
+	if d, ok := p.wait(1, 300*time.Millisecond); !ok || d != 300*time.Millisecond {
+		t.Errorf("wait() = %s, %v, want retry-after", d, ok)
+	}
+}
@@ -0,0 +1,530 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午2:05:00
+// @Desc 根据 Go 类型生成 JSON Schema，并按 schema 校验 JSON 数据
+package ai_sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/global"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	schemaTagKey            = "jsonschema"             // 例: `jsonschema:"description=城市名,enum=a,enum=b,minimum=1,required"`
+	schemaDescriptionTagKey = "jsonschema_description" // 描述中包含逗号时使用
+)
+
+var (
+	schemaUnsupportedErr = errors.New("unsupported schema type")  // 无法转换为 JSON Schema 的 Go 类型
+	schemaValidateErr    = errors.New("schema validation failed") // 数据不符合 schema
+	schemaTagErr         = errors.New("invalid jsonschema tag")   // jsonschema 标签格式错误
+)
+
+// SchemaOf 根据 T 的类型生成 JSON Schema，结构体字段名取自 json 标签
+// strict 为 true 时按结构化输出的严格模式生成：所有字段必填且对象不允许额外属性，指针与 omitempty 字段可以为 null
+func SchemaOf[T any](strict bool) (Property, error) {
+	return schemaOfType(reflect.TypeOf((*T)(nil)).Elem(), strict)
+}
+
+// NewFunction 根据参数结构体 T 生成工具方法定义，参数的解码类型与 schema 来自同一结构体
+// 字段名取自 json 标签，描述、枚举、取值范围等取自 jsonschema 标签；strict 为 true 时生成严格模式的 schema
+func NewFunction[T any](name string, description string, strict bool) (Function, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	schema, err := schemaOfType(t, strict)
+	if err != nil {
+		return Function{}, fmt.Errorf("NewFunction %s schemaOfType(%s): %w", name, t, err)
+	}
+	if schema.Type != global.ObjType {
+		return Function{}, fmt.Errorf("NewFunction %s: %w: parameters must be a struct", name, schemaUnsupportedErr)
+	}
+	return Function{
+		Name:        name,
+		Description: description,
+		Parameters: FunctionParameter{
+			Type:                 schema.Type,
+			Properties:           schema.Properties,
+			Required:             schema.Required,
+			AdditionalProperties: schema.AdditionalProperties,
+		},
+		Strict: strict,
+	}, nil
+}
+
+func schemaOfType(t reflect.Type, strict bool) (Property, error) {
+	b := schemaBuilder{strict: strict, visiting: make(map[reflect.Type]bool)}
+	return b.build(t)
+}
+
+type schemaBuilder struct {
+	strict   bool
+	visiting map[reflect.Type]bool // 检测递归类型
+}
+
+func (b *schemaBuilder) build(t reflect.Type) (Property, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return Property{Type: global.StringType}, nil
+	case reflect.Bool:
+		return Property{Type: global.BoolType}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Property{Type: global.IntType}, nil
+	case reflect.Float32, reflect.Float64:
+		return Property{Type: global.NumberType}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 { // []byte 以 base64 字符串编码
+			return Property{Type: global.StringType}, nil
+		}
+		items, err := b.build(t.Elem())
+		if err != nil {
+			return Property{}, err
+		}
+		return Property{Type: global.ArrayType, Items: &items}, nil
+	case reflect.Map:
+		if b.strict {
+			return Property{}, fmt.Errorf("%w: map %s is not allowed in strict mode", schemaUnsupportedErr, t)
+		}
+		if t.Key().Kind() != reflect.String {
+			return Property{}, fmt.Errorf("%w: map key %s", schemaUnsupportedErr, t.Key())
+		}
+		value, err := b.build(t.Elem())
+		if err != nil {
+			return Property{}, err
+		}
+		return Property{Type: global.ObjType, AdditionalProperties: value}, nil
+	case reflect.Struct:
+		return b.buildStruct(t)
+	case reflect.Interface:
+		if b.strict {
+			return Property{}, fmt.Errorf("%w: interface %s is not allowed in strict mode", schemaUnsupportedErr, t)
+		}
+		return Property{}, nil // 任意类型
+	default:
+		return Property{}, fmt.Errorf("%w: %s", schemaUnsupportedErr, t)
+	}
+}
+
+func (b *schemaBuilder) buildStruct(t reflect.Type) (Property, error) {
+	if b.visiting[t] {
+		return Property{}, fmt.Errorf("%w: recursive type %s", schemaUnsupportedErr, t)
+	}
+	b.visiting[t] = true
+	defer delete(b.visiting, t)
+
+	prop := Property{Type: global.ObjType, Properties: make(Properties)}
+	if b.strict {
+		prop.AdditionalProperties = false
+	}
+	if err := b.addFields(&prop, t); err != nil {
+		return Property{}, err
+	}
+	return prop, nil
+}
+
+// addFields 将结构体字段加入 prop，匿名嵌入且无 json 名称的结构体字段会被展开
+func (b *schemaBuilder) addFields(prop *Property, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := b.addFields(prop, ft); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fieldProp, err := b.build(field.Type)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		required, err := applySchemaTag(&fieldProp, field)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if b.strict && (omitempty || field.Type.Kind() == reflect.Pointer) {
+			fieldProp = nullable(fieldProp) // 严格模式下字段必填，以 null 表示未设置
+		}
+		prop.Properties[name] = fieldProp
+		if b.strict || !omitempty || required {
+			prop.Required = append(prop.Required, name)
+		}
+	}
+	return nil
+}
+
+// nullable 允许 prop 取 null，描述保留在外层
+func nullable(prop Property) Property {
+	description := prop.Description
+	prop.Description = ""
+	return Property{Description: description, AnyOf: []Property{prop, {Type: global.NullType}}}
+}
+
+// jsonFieldName 解析字段的 json 标签
+func jsonFieldName(field reflect.StructField) (name string, omitempty bool, skip bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return "", false, !field.Anonymous && !field.IsExported()
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" || opt == "omitzero" {
+			omitempty = true
+		}
+	}
+	return parts[0], omitempty, false
+}
+
+// applySchemaTag 将字段的 jsonschema 标签应用到 prop，enum 作用于数组字段时约束其元素
+func applySchemaTag(prop *Property, field reflect.StructField) (required bool, err error) {
+	for _, item := range strings.Split(field.Tag.Get(schemaTagKey), ",") {
+		key, value, _ := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		switch key {
+		case "":
+		case "required":
+			required = true
+		case "description":
+			prop.Description = value
+		case "enum":
+			target := prop
+			if prop.Type == global.ArrayType && prop.Items != nil {
+				target = prop.Items
+			}
+			if _, err = enumValue(target.Type, value); err != nil {
+				err = fmt.Errorf("%w: %w", schemaTagErr, err)
+			} else {
+				target.Enum = append(target.Enum, value)
+			}
+		case "minimum":
+			prop.Minimum, err = parseTagFloat(key, value)
+		case "maximum":
+			prop.Maximum, err = parseTagFloat(key, value)
+		case "minLength":
+			prop.MinLength, err = parseTagInt(key, value)
+		case "maxLength":
+			prop.MaxLength, err = parseTagInt(key, value)
+		case "minItems":
+			prop.MinItems, err = parseTagInt(key, value)
+		case "maxItems":
+			prop.MaxItems, err = parseTagInt(key, value)
+		default:
+			return false, fmt.Errorf("%w: unknown key %q", schemaTagErr, key)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	if desc, ok := field.Tag.Lookup(schemaDescriptionTagKey); ok {
+		prop.Description = desc
+	}
+	return required, nil
+}
+
+// enumValue 按 schema 类型解析枚举值，使枚举值的 JSON 类型与 Type 一致，未声明类型时按字符串处理
+func enumValue(typ string, value string) (interface{}, error) {
+	var (
+		v   interface{}
+		err error
+	)
+	switch typ {
+	case global.StringType, "":
+		return value, nil
+	case global.IntType:
+		v, err = strconv.ParseInt(value, 10, 64)
+	case global.NumberType:
+		v, err = strconv.ParseFloat(value, 64)
+	case global.BoolType:
+		v, err = strconv.ParseBool(value)
+	default:
+		return nil, fmt.Errorf("enum does not apply to type %q", typ)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("enum %q is not a valid %s", value, typ)
+	}
+	return v, nil
+}
+
+// enumValues 按 Type 解析全部枚举值
+func (p Property) enumValues() ([]interface{}, error) {
+	values := make([]interface{}, len(p.Enum))
+	for i, e := range p.Enum {
+		v, err := enumValue(p.Type, e)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// propertyJSON 不带 MarshalJSON / UnmarshalJSON 的 Property
+type propertyJSON Property
+
+// MarshalJSON 枚举值按 Type 输出为对应的 JSON 类型，如 integer 的 []string{"1", "2"} 输出为 [1,2]
+func (p Property) MarshalJSON() ([]byte, error) {
+	if len(p.Enum) == 0 {
+		return json.Marshal(propertyJSON(p))
+	}
+	values, err := p.enumValues()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		propertyJSON
+		Enum []interface{} `json:"enum,omitempty"`
+	}{propertyJSON(p), values})
+}
+
+// UnmarshalJSON 枚举值中的数值与布尔值转为字符串
+func (p *Property) UnmarshalJSON(data []byte) error {
+	var v struct {
+		*propertyJSON
+		Enum []json.RawMessage `json:"enum,omitempty"`
+	}
+	v.propertyJSON = (*propertyJSON)(p)
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	p.Enum = nil
+	for _, raw := range v.Enum {
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			s = strings.TrimSpace(string(raw))
+		}
+		p.Enum = append(p.Enum, s)
+	}
+	return nil
+}
+
+func parseTagFloat(key, value string) (*float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s=%s: %w", schemaTagErr, key, value, err)
+	}
+	return &f, nil
+}
+
+func parseTagInt(key, value string) (*int, error) {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s=%s: %w", schemaTagErr, key, value, err)
+	}
+	return &i, nil
+}
+
+// validateJSON 校验 JSON 文本是否符合 schema
+func validateJSON(schema Property, data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return fmt.Errorf("%w: invalid json: %w", schemaValidateErr, err)
+	}
+	return validateValue(schema, v, "$")
+}
+
+// validateValue 校验 json 解码后的值 (需使用 UseNumber 解码) 是否符合 schema
+func validateValue(schema Property, v interface{}, path string) error {
+	if len(schema.Enum) != 0 && !enumContains(schema, v) {
+		return fmt.Errorf("%w: %s must be one of %v", schemaValidateErr, path, schema.Enum)
+	}
+	if len(schema.AnyOf) != 0 {
+		matched := false
+		for _, sub := range schema.AnyOf {
+			if validateValue(sub, v, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%w: %s must match at least one schema in anyOf", schemaValidateErr, path)
+		}
+	}
+	if len(schema.OneOf) != 0 {
+		matched := 0
+		for _, sub := range schema.OneOf {
+			if validateValue(sub, v, path) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%w: %s must match exactly one schema in oneOf, matched %d", schemaValidateErr, path, matched)
+		}
+	}
+	switch schema.Type {
+	case global.ObjType:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%w: %s must be an object", schemaValidateErr, path)
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%w: %s.%s is required", schemaValidateErr, path, name)
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // 保证错误信息稳定
+		for _, k := range keys {
+			if sub, ok := schema.Properties[k]; ok {
+				if err := validateValue(sub, obj[k], path+"."+k); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema.AdditionalProperties.(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%w: %s.%s is not allowed", schemaValidateErr, path, k)
+				}
+			case Property:
+				if err := validateValue(additional, obj[k], path+"."+k); err != nil {
+					return err
+				}
+			case *Property:
+				if additional == nil {
+					continue
+				}
+				if err := validateValue(*additional, obj[k], path+"."+k); err != nil {
+					return err
+				}
+			}
+		}
+	case global.ArrayType:
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%w: %s must be an array", schemaValidateErr, path)
+		}
+		if schema.MinItems != nil && len(arr) < *schema.MinItems {
+			return fmt.Errorf("%w: %s must contain at least %d items", schemaValidateErr, path, *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(arr) > *schema.MaxItems {
+			return fmt.Errorf("%w: %s must contain at most %d items", schemaValidateErr, path, *schema.MaxItems)
+		}
+		if schema.Items != nil {
+			for i, item := range arr {
+				if err := validateValue(*schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case global.StringType:
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%w: %s must be a string", schemaValidateErr, path)
+		}
+		if schema.MinLength != nil && utf8.RuneCountInString(str) < *schema.MinLength {
+			return fmt.Errorf("%w: %s must be at least %d characters", schemaValidateErr, path, *schema.MinLength)
+		}
+		if schema.MaxLength != nil && utf8.RuneCountInString(str) > *schema.MaxLength {
+			return fmt.Errorf("%w: %s must be at most %d characters", schemaValidateErr, path, *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			re, err := regexp.Compile(schema.Pattern)
+			if err != nil {
+				return fmt.Errorf("%w: %s pattern %q: %w", schemaValidateErr, path, schema.Pattern, err)
+			}
+			if !re.MatchString(str) {
+				return fmt.Errorf("%w: %s must match pattern %q", schemaValidateErr, path, schema.Pattern)
+			}
+		}
+	case global.IntType:
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("%w: %s must be an integer", schemaValidateErr, path)
+		}
+		if _, err := n.Int64(); err != nil {
+			if f, err := n.Float64(); err != nil || f != float64(int64(f)) {
+				return fmt.Errorf("%w: %s must be an integer", schemaValidateErr, path)
+			}
+		}
+		return validateRange(schema, n, path)
+	case global.NumberType:
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("%w: %s must be a number", schemaValidateErr, path)
+		}
+		return validateRange(schema, n, path)
+	case global.BoolType:
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%w: %s must be a boolean", schemaValidateErr, path)
+		}
+	case global.NullType:
+		if v != nil {
+			return fmt.Errorf("%w: %s must be null", schemaValidateErr, path)
+		}
+	}
+	return nil
+}
+
+// validateRange 校验数值范围
+func validateRange(schema Property, n json.Number, path string) error {
+	f, err := n.Float64()
+	if err != nil {
+		return fmt.Errorf("%w: %s must be a number", schemaValidateErr, path)
+	}
+	if schema.Minimum != nil && f < *schema.Minimum {
+		return fmt.Errorf("%w: %s must be >= %v", schemaValidateErr, path, *schema.Minimum)
+	}
+	if schema.Maximum != nil && f > *schema.Maximum {
+		return fmt.Errorf("%w: %s must be <= %v", schemaValidateErr, path, *schema.Maximum)
+	}
+	return nil
+}
+
+// enumContains 判断 json 解码后的值是否为枚举值之一，枚举值按 Type 解析，数值按大小比较
+func enumContains(schema Property, v interface{}) bool {
+	values, err := schema.enumValues()
+	if err != nil {
+		return false
+	}
+	n, isNumber := v.(json.Number)
+	for _, e := range values {
+		switch e := e.(type) {
+		case int64:
+			if f, err := n.Float64(); isNumber && err == nil && f == float64(e) {
+				return true
+			}
+		case float64:
+			if f, err := n.Float64(); isNumber && err == nil && f == e {
+				return true
+			}
+		default:
+			if e == v {
+				return true
+			}
+		}
+	}
+	return false
+}
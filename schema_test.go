@@ -0,0 +1,267 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午4:10:00
+// @Desc JSON Schema 生成与校验测试
+package ai_sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Clov614/go-ai-sdk/example_func_call/weather"
+	"github.com/Clov614/go-ai-sdk/global"
+	"reflect"
+	"testing"
+)
+
+type testAddress struct {
+	City string `json:"city"`
+}
+
+type testPerson struct {
+	Name     string        `json:"name"`
+	Nickname *string       `json:"nickname"`
+	Age      int           `json:"age"`
+	Tags     []string      `json:"tags,omitempty"`
+	Address  testAddress   `json:"address"`
+	History  []testAddress `json:"history"`
+	Ignored  string        `json:"-"`
+	secret   string
+}
+
+func TestSchemaOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		strict bool
+		want   string
+	}{
+		{
+			name:   "strict",
+			strict: true,
+			want:   `{"type":"object","properties":{"address":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"],"additionalProperties":false},"age":{"type":"integer"},"history":{"type":"array","items":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"],"additionalProperties":false}},"name":{"type":"string"},"nickname":{"anyOf":[{"type":"string"},{"type":"null"}]},"tags":{"anyOf":[{"type":"array","items":{"type":"string"}},{"type":"null"}]}},"required":["name","nickname","age","tags","address","history"],"additionalProperties":false}`,
+		},
+		{
+			name:   "omitempty is optional",
+			strict: false,
+			want:   `{"type":"object","properties":{"address":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]},"age":{"type":"integer"},"history":{"type":"array","items":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}},"name":{"type":"string"},"nickname":{"type":"string"},"tags":{"type":"array","items":{"type":"string"}}},"required":["name","nickname","age","address","history"]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := SchemaOf[testPerson](tt.strict)
+			if err != nil {
+				t.Fatalf("SchemaOf() error = %v", err)
+			}
+			got, _ := json.Marshal(schema)
+			if string(got) != tt.want {
+				t.Errorf("SchemaOf() = %s\n want %s", got, tt.want)
+			}
+		})
+	}
+	// 严格模式下未设置的指针与 omitempty 字段以 null 输出，仍需通过校验并还原
+	schema, err := SchemaOf[testPerson](true)
+	if err != nil {
+		t.Fatalf("SchemaOf() error = %v", err)
+	}
+	data := []byte(`{"name":"clover","nickname":null,"age":18,"tags":null,"address":{"city":"泉州"},"history":[]}`)
+	if err = validateJSON(schema, data); err != nil {
+		t.Errorf("validateJSON() null optional fields error = %v", err)
+	}
+	var person testPerson
+	if err = json.Unmarshal(data, &person); err != nil || person.Nickname != nil || person.Tags != nil {
+		t.Errorf("json.Unmarshal() = %+v, %v, want nil nickname and tags", person, err)
+	}
+	if err = validateJSON(schema, []byte(`{"name":"clover","age":18,"tags":null,"address":{"city":"泉州"},"history":[]}`)); err == nil {
+		t.Errorf("validateJSON() missing nickname error = nil, want required")
+	}
+	if _, err := SchemaOf[map[string]int](true); err == nil {
+		t.Errorf("SchemaOf[map]() strict error = nil, want unsupported")
+	}
+}
+
+type testSearchArgs struct {
+	Keyword string   `json:"keyword" jsonschema:"description=搜索关键词,minLength=1"`
+	Limit   int      `json:"limit,omitempty" jsonschema:"minimum=1,maximum=50"`
+	Sort    string   `json:"sort,omitempty" jsonschema:"enum=time,enum=hot,required"`
+	Sources []string `json:"sources,omitempty" jsonschema:"enum=web,enum=news,maxItems=2" jsonschema_description:"来源, 可多选"`
+}
+
+func TestNewFunction(t *testing.T) {
+	fn, err := NewFunction[weather.Args]("get_weather_by_city", "根据地址获取天气", false)
+	if err != nil {
+		t.Fatalf("NewFunction() error = %v", err)
+	}
+	got, _ := json.Marshal(fn)
+	want := `{"name":"get_weather_by_city","description":"根据地址获取天气","parameters":{"type":"object","properties":{"city_addr":{"type":"string","description":"地址，如：国家，城市，县、区地址"},"is_multi":{"type":"boolean","description":"是否获取多日天气"}},"required":["city_addr","is_multi"]},"strict":false}`
+	if string(got) != want {
+		t.Errorf("NewFunction() = %s\n want %s", got, want)
+	}
+
+	fn, err = NewFunction[testSearchArgs]("search", "搜索", false)
+	if err != nil {
+		t.Fatalf("NewFunction() error = %v", err)
+	}
+	got, _ = json.Marshal(fn.Parameters)
+	want = `{"type":"object","properties":{"keyword":{"type":"string","description":"搜索关键词","minLength":1},"limit":{"type":"integer","minimum":1,"maximum":50},"sort":{"type":"string","enum":["time","hot"]},"sources":{"type":"array","description":"来源, 可多选","items":{"type":"string","enum":["web","news"]},"maxItems":2}},"required":["keyword","sort"]}`
+	if string(got) != want {
+		t.Errorf("NewFunction().Parameters = %s\n want %s", got, want)
+	}
+
+	if _, err = NewFunction[struct {
+		A int `json:"a" jsonschema:"minimum=x"`
+	}]("bad", "", false); !errors.Is(err, schemaTagErr) {
+		t.Errorf("NewFunction() bad tag error = %v, want schemaTagErr", err)
+	}
+}
+
+type testEnumArgs struct {
+	Level  int      `json:"level" jsonschema:"enum=1,enum=2"`
+	Ratio  float64  `json:"ratio" jsonschema:"enum=0.5,enum=1"`
+	Notify bool     `json:"notify" jsonschema:"enum=true"`
+	Codes  []uint16 `json:"codes" jsonschema:"enum=200,enum=404"`
+}
+
+func TestNewFunction_enum(t *testing.T) {
+	fn, err := NewFunction[testEnumArgs]("enum", "", false)
+	if err != nil {
+		t.Fatalf("NewFunction() error = %v", err)
+	}
+	got, _ := json.Marshal(fn.Parameters.Properties)
+	want := `{"codes":{"type":"array","items":{"type":"integer","enum":[200,404]}},"level":{"type":"integer","enum":[1,2]},"notify":{"type":"boolean","enum":[true]},"ratio":{"type":"number","enum":[0.5,1]}}`
+	if string(got) != want {
+		t.Errorf("NewFunction().Parameters.Properties = %s\n want %s", got, want)
+	}
+	if err = fn.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	schema, err := SchemaOf[testEnumArgs](false)
+	if err != nil {
+		t.Fatalf("SchemaOf() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: `{"level":2,"ratio":1.0,"notify":true,"codes":[404]}`},
+		{name: "integer not in enum", data: `{"level":3,"ratio":0.5,"notify":true,"codes":[]}`, wantErr: true},
+		{name: "string for integer enum", data: `{"level":"1","ratio":0.5,"notify":true,"codes":[]}`, wantErr: true},
+		{name: "boolean not in enum", data: `{"level":1,"ratio":0.5,"notify":false,"codes":[]}`, wantErr: true},
+		{name: "item not in enum", data: `{"level":1,"ratio":0.5,"notify":true,"codes":[500]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateJSON(schema, []byte(tt.data)); (err != nil) != tt.wantErr {
+				t.Errorf("validateJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err = NewFunction[struct {
+		Level int `json:"level" jsonschema:"enum=low"`
+	}]("bad", "", false); !errors.Is(err, schemaTagErr) {
+		t.Errorf("NewFunction() invalid integer enum error = %v, want schemaTagErr", err)
+	}
+	if _, err = NewFunction[struct {
+		Address testAddress `json:"address" jsonschema:"enum=a"`
+	}]("bad", "", false); !errors.Is(err, schemaTagErr) {
+		t.Errorf("NewFunction() object enum error = %v, want schemaTagErr", err)
+	}
+}
+
+func TestProperty_JSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		prop    Property
+		want    string
+		wantErr bool
+	}{
+		{name: "string", prop: Property{Type: global.StringType, Enum: []string{"c", "f"}}, want: `{"type":"string","enum":["c","f"]}`},
+		{name: "integer", prop: Property{Type: global.IntType, Enum: []string{"1", "2"}}, want: `{"type":"integer","enum":[1,2]}`},
+		{name: "number", prop: Property{Type: global.NumberType, Enum: []string{"0.5"}}, want: `{"type":"number","enum":[0.5]}`},
+		{name: "boolean items", prop: Property{Type: global.ArrayType, Items: &Property{Type: global.BoolType, Enum: []string{"true"}}},
+			want: `{"type":"array","items":{"type":"boolean","enum":[true]}}`},
+		{name: "invalid integer", prop: Property{Type: global.IntType, Enum: []string{"low"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.prop)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("json.Marshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("json.Marshal() = %s, want %s", got, tt.want)
+			}
+			var back Property
+			if err = json.Unmarshal(got, &back); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(back, tt.prop) {
+				t.Errorf("json.Unmarshal() = %+v, want %+v", back, tt.prop)
+			}
+		})
+	}
+}
+
+func Test_validateJSON(t *testing.T) {
+	schema, err := SchemaOf[testSearchArgs](false)
+	if err != nil {
+		t.Fatalf("SchemaOf() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: `{"keyword":"go","limit":10,"sort":"hot","sources":["web"]}`},
+		{name: "missing required", data: `{"keyword":"go"}`, wantErr: true},
+		{name: "enum", data: `{"keyword":"go","sort":"name"}`, wantErr: true},
+		{name: "maximum", data: `{"keyword":"go","sort":"hot","limit":51}`, wantErr: true},
+		{name: "integer", data: `{"keyword":"go","sort":"hot","limit":1.5}`, wantErr: true},
+		{name: "minLength", data: `{"keyword":"","sort":"hot"}`, wantErr: true},
+		{name: "maxItems", data: `{"keyword":"go","sort":"hot","sources":["web","news","web"]}`, wantErr: true},
+		{name: "item enum", data: `{"keyword":"go","sort":"hot","sources":["blog"]}`, wantErr: true},
+		{name: "invalid json", data: `{"keyword":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateJSON(schema, []byte(tt.data)); (err != nil) != tt.wantErr {
+				t.Errorf("validateJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_validateJSON_composition(t *testing.T) {
+	schema := Property{
+		Type: global.ObjType,
+		Properties: Properties{
+			"code": {Type: global.StringType, Pattern: `^[A-Z]{3}-\d+$`},
+			"note": {AnyOf: []Property{{Type: global.StringType}, {Type: global.NullType}}},
+			"id":   {OneOf: []Property{{Type: global.IntType}, {Type: global.NumberType, Minimum: Ptr(100.0)}}},
+		},
+		AdditionalProperties: Property{Type: global.BoolType},
+	}
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: `{"code":"ABC-1","note":null,"id":150.5,"extra":true}`},
+		{name: "pattern", data: `{"code":"abc-1"}`, wantErr: true},
+		{name: "anyOf", data: `{"note":1}`, wantErr: true},
+		{name: "oneOf matches both", data: `{"id":200}`, wantErr: true},
+		{name: "oneOf matches none", data: `{"id":"1"}`, wantErr: true},
+		{name: "additionalProperties schema", data: `{"extra":"yes"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateJSON(schema, []byte(tt.data)); (err != nil) != tt.wantErr {
+				t.Errorf("validateJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
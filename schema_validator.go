@@ -0,0 +1,261 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午6:10:00
+// @Desc 校验工具方法定义与参数 schema 本身是否合法，在注册时拒绝错误的定义
+package ai_sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/global"
+	"regexp"
+	"sort"
+)
+
+var (
+	schemaInvalidErr = errors.New("invalid schema") // schema 定义不合法
+	funcNameRegexp   = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+// schemaTypes JSON Schema 支持的类型
+var schemaTypes = map[string]bool{
+	global.ObjType:    true,
+	global.StringType: true,
+	global.IntType:    true,
+	global.NumberType: true,
+	global.BoolType:   true,
+	global.ArrayType:  true,
+	global.NullType:   true,
+}
+
+// Validate 校验方法定义：名称需匹配 ^[a-zA-Z0-9_-]{1,64}$，参数需为合法的 object schema
+// Strict 为 true 时还要求所有对象禁止额外属性 (additionalProperties=false) 且所有属性必填
+func (f Function) Validate() error {
+	if !funcNameRegexp.MatchString(f.Name) {
+		return fmt.Errorf("%w: function name %q must match %s", schemaInvalidErr, f.Name, funcNameRegexp)
+	}
+	if err := f.Parameters.Validate(); err != nil {
+		return fmt.Errorf("function %s parameters: %w", f.Name, err)
+	}
+	if f.Strict {
+		if err := validateStrictSchema(f.Parameters.property(), "$"); err != nil {
+			return fmt.Errorf("function %s parameters: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// Validate 校验参数 schema，根节点必须为 object
+func (p FunctionParameter) Validate() error {
+	if p.Type != global.ObjType {
+		return fmt.Errorf("%w: $ type must be %q, got %q", schemaInvalidErr, global.ObjType, p.Type)
+	}
+	return p.property().Validate()
+}
+
+// Validate 校验 schema 定义：类型合法、关键字与类型匹配、取值范围自洽、正则可编译、默认值符合 schema
+func (p Property) Validate() error {
+	return validateSchema(p, "$")
+}
+
+// property 将参数定义转换为等价的 Property，便于统一校验
+func (p FunctionParameter) property() Property {
+	return Property{
+		Type:                 p.Type,
+		Properties:           p.Properties,
+		Required:             p.Required,
+		AdditionalProperties: p.AdditionalProperties,
+	}
+}
+
+func validateSchema(p Property, path string) error {
+	if p.Type != "" && !schemaTypes[p.Type] { // 未声明类型表示任意值
+		return fmt.Errorf("%w: %s unknown type %q", schemaInvalidErr, path, p.Type)
+	}
+	if err := validateSchemaKeywords(p, path); err != nil {
+		return err
+	}
+
+	for _, name := range sortedPropertyNames(p.Properties) {
+		sub := p.Properties[name]
+		if name == "" {
+			return fmt.Errorf("%w: %s has an empty property name", schemaInvalidErr, path)
+		}
+		if err := validateSchema(sub, path+"."+name); err != nil {
+			return err
+		}
+	}
+	seen := make(map[string]bool, len(p.Required))
+	for _, name := range p.Required {
+		if _, ok := p.Properties[name]; !ok {
+			return fmt.Errorf("%w: %s required property %q is not defined", schemaInvalidErr, path, name)
+		}
+		if seen[name] {
+			return fmt.Errorf("%w: %s required property %q is duplicated", schemaInvalidErr, path, name)
+		}
+		seen[name] = true
+	}
+	switch additional := p.AdditionalProperties.(type) {
+	case nil, bool:
+	case Property:
+		if err := validateSchema(additional, path+".additionalProperties"); err != nil {
+			return err
+		}
+	case *Property:
+		if additional != nil {
+			if err := validateSchema(*additional, path+".additionalProperties"); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %s additionalProperties must be a bool or Property, got %T", schemaInvalidErr, path, additional)
+	}
+	if p.Items != nil {
+		if err := validateSchema(*p.Items, path+"[]"); err != nil {
+			return err
+		}
+	}
+	for i, sub := range p.AnyOf {
+		if err := validateSchema(sub, fmt.Sprintf("%s.anyOf[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	for i, sub := range p.OneOf {
+		if err := validateSchema(sub, fmt.Sprintf("%s.oneOf[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+
+	if p.Default != nil {
+		v, err := normalizeJSONValue(p.Default)
+		if err != nil {
+			return fmt.Errorf("%w: %s default: %w", schemaInvalidErr, path, err)
+		}
+		if err = validateValue(p, v, path); err != nil {
+			return fmt.Errorf("%w: %s default does not match schema: %w", schemaInvalidErr, path, err)
+		}
+	}
+	return nil
+}
+
+// validateSchemaKeywords 校验关键字是否与类型匹配以及取值范围是否自洽
+func validateSchemaKeywords(p Property, path string) error {
+	isObj, isArr := p.Type == global.ObjType, p.Type == global.ArrayType
+	isStr, isNum := p.Type == global.StringType, p.Type == global.NumberType || p.Type == global.IntType
+
+	if !isObj && (len(p.Properties) != 0 || len(p.Required) != 0 || p.AdditionalProperties != nil) {
+		return fmt.Errorf("%w: %s properties/required/additionalProperties only apply to %s, got %q", schemaInvalidErr, path, global.ObjType, p.Type)
+	}
+	if isArr && p.Items == nil {
+		return fmt.Errorf("%w: %s array must define items", schemaInvalidErr, path)
+	}
+	if !isArr && (p.Items != nil || p.MinItems != nil || p.MaxItems != nil) {
+		return fmt.Errorf("%w: %s items/minItems/maxItems only apply to %s, got %q", schemaInvalidErr, path, global.ArrayType, p.Type)
+	}
+	if !isStr && (p.MinLength != nil || p.MaxLength != nil || p.Pattern != "") {
+		return fmt.Errorf("%w: %s minLength/maxLength/pattern only apply to %s, got %q", schemaInvalidErr, path, global.StringType, p.Type)
+	}
+	if !isNum && (p.Minimum != nil || p.Maximum != nil) {
+		return fmt.Errorf("%w: %s minimum/maximum only apply to %s or %s, got %q", schemaInvalidErr, path, global.NumberType, global.IntType, p.Type)
+	}
+	if len(p.Enum) != 0 && (isObj || isArr) {
+		return fmt.Errorf("%w: %s enum does not apply to %q", schemaInvalidErr, path, p.Type)
+	}
+	if _, err := p.enumValues(); err != nil { // 枚举值需能按 Type 解析，否则没有值能满足 schema
+		return fmt.Errorf("%w: %s %w", schemaInvalidErr, path, err)
+	}
+
+	if p.Minimum != nil && p.Maximum != nil && *p.Minimum > *p.Maximum {
+		return fmt.Errorf("%w: %s minimum %v > maximum %v", schemaInvalidErr, path, *p.Minimum, *p.Maximum)
+	}
+	if err := validateSchemaBounds(p.MinLength, p.MaxLength, "minLength", "maxLength", path); err != nil {
+		return err
+	}
+	if err := validateSchemaBounds(p.MinItems, p.MaxItems, "minItems", "maxItems", path); err != nil {
+		return err
+	}
+	if p.Pattern != "" {
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("%w: %s pattern %q: %w", schemaInvalidErr, path, p.Pattern, err)
+		}
+	}
+	return nil
+}
+
+func validateSchemaBounds(min, max *int, minKey, maxKey string, path string) error {
+	if min != nil && *min < 0 {
+		return fmt.Errorf("%w: %s %s must be >= 0", schemaInvalidErr, path, minKey)
+	}
+	if max != nil && *max < 0 {
+		return fmt.Errorf("%w: %s %s must be >= 0", schemaInvalidErr, path, maxKey)
+	}
+	if min != nil && max != nil && *min > *max {
+		return fmt.Errorf("%w: %s %s %d > %s %d", schemaInvalidErr, path, minKey, *min, maxKey, *max)
+	}
+	return nil
+}
+
+// validateStrictSchema 校验严格模式的约束：对象禁止额外属性，且所有属性必填
+func validateStrictSchema(p Property, path string) error {
+	if p.Type == global.ObjType {
+		if additional, ok := p.AdditionalProperties.(bool); !ok || additional {
+			return fmt.Errorf("%w: %s strict mode requires additionalProperties=false", schemaInvalidErr, path)
+		}
+		required := make(map[string]bool, len(p.Required))
+		for _, name := range p.Required {
+			required[name] = true
+		}
+		for _, name := range sortedPropertyNames(p.Properties) {
+			sub := p.Properties[name]
+			if !required[name] {
+				return fmt.Errorf("%w: %s strict mode requires property %q to be required", schemaInvalidErr, path, name)
+			}
+			if err := validateStrictSchema(sub, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if p.Items != nil {
+		if err := validateStrictSchema(*p.Items, path+"[]"); err != nil {
+			return err
+		}
+	}
+	for i, sub := range p.AnyOf {
+		if err := validateStrictSchema(sub, fmt.Sprintf("%s.anyOf[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	for i, sub := range p.OneOf {
+		if err := validateStrictSchema(sub, fmt.Sprintf("%s.oneOf[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedPropertyNames 按名称排序遍历属性，保证错误信息稳定
+func sortedPropertyNames(properties Properties) []string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// normalizeJSONValue 将 Go 值转换为 json 解码后的形式 (数字为 json.Number)，以便复用 validateValue
+func normalizeJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var normalized interface{}
+	if err = decoder.Decode(&normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
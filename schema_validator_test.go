@@ -0,0 +1,122 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午6:40:00
+// @Desc 方法定义与 schema 校验测试
+package ai_sdk
+
+import (
+	"errors"
+	"github.com/Clov614/go-ai-sdk/global"
+	"testing"
+)
+
+func TestFunction_Validate(t *testing.T) {
+	str := Property{Type: global.StringType}
+	tests := []struct {
+		name     string
+		function Function
+		wantErr  bool
+	}{
+		{
+			name: "nested object and array",
+			function: Function{Name: "create_order", Parameters: FunctionParameter{
+				Type: global.ObjType,
+				Properties: Properties{
+					"items": {Type: global.ArrayType, MinItems: Ptr(1), Items: &Property{
+						Type: global.ObjType,
+						Properties: Properties{
+							"sku":   {Type: global.StringType, Pattern: `^[A-Z]{3}-\d+$`},
+							"count": {Type: global.IntType, Minimum: Ptr(1.0), Default: 1},
+							"price": {Type: global.NumberType, Minimum: Ptr(0.0)},
+						},
+						Required:             []string{"sku"},
+						AdditionalProperties: false,
+					}},
+					"note":   {AnyOf: []Property{str, {Type: global.NullType}}},
+					"labels": {Type: global.ObjType, AdditionalProperties: str},
+				},
+				Required: []string{"items"},
+			}},
+		},
+		{name: "empty parameters", function: Function{Name: "ping", Parameters: FunctionParameter{Type: global.ObjType}}},
+		{name: "invalid name", function: Function{Name: "get weather", Parameters: FunctionParameter{Type: global.ObjType}}, wantErr: true},
+		{name: "root not object", function: Function{Name: "f", Parameters: FunctionParameter{Type: global.StringType}}, wantErr: true},
+		{name: "unknown type", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"a": {Type: "float"}},
+		}}, wantErr: true},
+		{name: "required undefined", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"a": str}, Required: []string{"b"},
+		}}, wantErr: true},
+		{name: "array without items", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"a": {Type: global.ArrayType}},
+		}}, wantErr: true},
+		{name: "minimum on string", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"a": {Type: global.StringType, Minimum: Ptr(1.0)}},
+		}}, wantErr: true},
+		{name: "minimum > maximum", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"a": {Type: global.IntType, Minimum: Ptr(2.0), Maximum: Ptr(1.0)}},
+		}}, wantErr: true},
+		{name: "invalid pattern", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"a": {Type: global.StringType, Pattern: "("}},
+		}}, wantErr: true},
+		{name: "default mismatch", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"a": {Type: global.IntType, Default: "one"}},
+		}}, wantErr: true},
+		{name: "integer enum", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"level": {Type: global.IntType, Enum: []string{"1", "2"}}},
+		}}},
+		{name: "string enum on integer", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"level": {Type: global.IntType, Enum: []string{"low", "high"}}},
+		}}, wantErr: true},
+		{name: "fractional enum on integer", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"level": {Type: global.IntType, Enum: []string{"1.5"}}},
+		}}, wantErr: true},
+		{name: "string enum on boolean", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"on": {Type: global.BoolType, Enum: []string{"yes"}}},
+		}}, wantErr: true},
+		{name: "nested error in anyOf", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"a": {AnyOf: []Property{{Type: global.ArrayType}}}},
+		}}, wantErr: true},
+		{name: "invalid additionalProperties", function: Function{Name: "f", Parameters: FunctionParameter{
+			Type: global.ObjType, AdditionalProperties: "no",
+		}}, wantErr: true},
+		{name: "strict requires all properties", function: Function{Name: "f", Strict: true, Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"a": str}, AdditionalProperties: false,
+		}}, wantErr: true},
+		{name: "strict ok", function: Function{Name: "f", Strict: true, Parameters: FunctionParameter{
+			Type: global.ObjType, Properties: Properties{"a": str}, Required: []string{"a"}, AdditionalProperties: false,
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.function.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, schemaInvalidErr) {
+				t.Errorf("Validate() error = %v, want schemaInvalidErr", err)
+			}
+		})
+	}
+}
+
+func TestFuncCallRegister_Register(t *testing.T) {
+	fc := &FuncCallRegister{
+		Name2Info:           make(map[string]*FuncCallInfo),
+		keyWord2FuncNameMap: make(map[string]funcInfoList),
+		filter:              make(filter, 0),
+	}
+	err := fc.Register(&FuncCallInfo{
+		Function: Function{Name: "bad", Parameters: FunctionParameter{Type: global.ObjType, Required: []string{"x"}}},
+		CallFunc: echoCallFunc{},
+	}, []string{"bad"})
+	if !errors.Is(err, funcRegisterErr) || !errors.Is(err, schemaInvalidErr) {
+		t.Errorf("Register() error = %v, want funcRegisterErr", err)
+	}
+	if fc.GetCallInfo("bad") != nil || len(*fc.GetToolsByContent("bad")) != 0 {
+		t.Error("invalid function should not be registered")
+	}
+	if err = fc.Register(&FuncCallInfo{Function: Function{Name: "nil_call", Parameters: FunctionParameter{Type: global.ObjType}}}, nil); !errors.Is(err, funcRegisterErr) {
+		t.Errorf("Register() nil CallFunc error = %v, want funcRegisterErr", err)
+	}
+}
@@ -0,0 +1,130 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午3:20:00
+// @Desc 显式初始化：ai_sdk.New 按选项创建客户端与会话主体，导入本包不会读写任何文件
+package ai_sdk
+
+import (
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/config"
+	"github.com/Clov614/go-ai-sdk/logging"
+	"github.com/Clov614/go-ai-sdk/tokenizer"
+)
+
+// SDK 通过 New 创建的客户端与会话主体
+type SDK struct {
+	Config  config.AICfg // 生效的配置
+	Client  *AIClient    // 根据配置创建的客户端
+	Session *Session     // 使用 Client 的会话主体
+}
+
+// Option New 的选项，按传入顺序生效
+type Option func(o *options) error
+
+type options struct {
+	cfg        config.AICfg
+	logFile    string
+	system     string
+	clientOpts []ClientOption
+}
+
+// WithConfig 使用 cfg 作为配置，覆盖此前的配置选项
+func WithConfig(cfg config.AICfg) Option {
+	return func(o *options) error {
+		o.cfg = cfg
+		return nil
+	}
+}
+
+// WithConfigFile 读取 yaml 配置文件，未设置的配置项使用默认值 (不会写入或生成文件)
+func WithConfigFile(path string) Option {
+	return func(o *options) (err error) {
+		o.cfg, err = config.LoadFile(path)
+		return err
+	}
+}
+
+// WithLogFile 将日志同时写入 path 文件
+func WithLogFile(path string) Option {
+	return func(o *options) error {
+		o.logFile = path
+		return nil
+	}
+}
+
+// WithClientOptions 创建客户端时追加的选项 (如 WithKeyQuarantineHandler、WithTransport)，在配置之后生效，可多次传入
+func WithClientOptions(opts ...ClientOption) Option {
+	return func(o *options) error {
+		o.clientOpts = append(o.clientOpts, opts...)
+		return nil
+	}
+}
+
+// WithSystemPrompt 会话主体的预设 (人设)，默认无预设
+func WithSystemPrompt(system string) Option {
+	return func(o *options) error {
+		o.system = system
+		return nil
+	}
+}
+
+// New 按选项创建客户端与会话主体，默认使用 config.DefaultConfig() 且不读写任何文件
+// 客户端与 NewClient 使用相同的校验，配置不合法 (未设置 api 密钥、超时为负数、未知的负载均衡策略等) 时返回错误
+// 每个 SDK 的会话主体使用自身的配置，不读取也不修改全局 config.Config
+// 为兼容使用全局变量的旧代码，New 会将全局客户端与 DefaultSession 替换为本次创建的实例
+// 被替换的 DefaultSession 不属于其它 SDK 时会被关闭，SDK 的会话主体由调用方通过 SDK.Close 关闭
+func New(opts ...Option) (*SDK, error) {
+	o := options{cfg: config.DefaultConfig()}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, fmt.Errorf("ai_sdk.New: %w", err)
+		}
+	}
+	if o.logFile != "" {
+		if err := logging.Setup(o.logFile); err != nil {
+			return nil, fmt.Errorf("ai_sdk.New: %w", err)
+		}
+	}
+	if o.cfg.TokenizerDir != "" {
+		tokenizer.SetVocabDir(o.cfg.TokenizerDir)
+	}
+	client, err := newClientFromConfig(o.cfg, o.clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("ai_sdk.New: %w", err)
+	}
+	sdk := &SDK{Config: o.cfg, Client: client}
+	cfg := o.cfg // 会话主体持有独立的配置，再次调用 New 不影响已创建的 SDK
+	sdk.Session = newSessionWithConfig(o.system, o.cfg.SessionTimeOut, &cfg)
+	sdk.Session.client = sdk.Client
+	sdk.Session.ownedBySDK = true
+	previous := DefaultSession
+	aiclient = sdk.Client
+	DefaultSession = sdk.Session
+	if previous != nil && !previous.ownedBySDK { // 包初始化时创建的会话主体无人持有，关闭以停止其存活计时
+		previous.Close()
+	}
+	return sdk, nil
+}
+
+// Close 关闭会话主体并停止全部存活计时，DefaultSession 为该会话主体时同样不可再使用
+func (s *SDK) Close() {
+	s.Session.Close()
+}
+
+// Init 兼容旧版本的初始化方式：读取 ./cfg/ai-cfg.yaml (不存在时生成模板)，日志写入 ./log/ai.log，词表从 ./cfg/tiktoken 加载，并设置 config.Config、全局客户端与 DefaultSession
+// 旧版本导入时自动完成这些操作，升级后需在程序启动时显式调用
+func Init() (*SDK, error) {
+	if err := config.Setup(config.DefaultPath); err != nil {
+		return nil, fmt.Errorf("ai_sdk.Init: %w", err)
+	}
+	cfg := config.Config
+	if cfg.TokenizerDir == "" {
+		cfg.TokenizerDir = tokenizer.DefaultVocabDir
+	}
+	sdk, err := New(WithConfig(cfg), WithLogFile(logging.DefaultLogPath))
+	if err != nil {
+		return nil, err
+	}
+	config.Config = sdk.Config // 兼容直接读取 config.Config 的旧代码
+	return sdk, nil
+}
@@ -0,0 +1,188 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午3:40:00
+// @Desc 显式初始化测试
+package ai_sdk
+
+import (
+	"errors"
+	"github.com/Clov614/go-ai-sdk/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSDKConfig 带测试密钥的默认配置，默认配置不含密钥无法通过 New 的校验
+func testSDKConfig() config.AICfg {
+	cfg := config.DefaultConfig()
+	cfg.ApiCfgs[0].AuthList = []string{"sk-test"}
+	return cfg
+}
+
+// restoreGlobals 恢复 New 替换的全局变量
+func restoreGlobals(t *testing.T) {
+	cfg, client, session := config.Config, aiclient, DefaultSession
+	t.Cleanup(func() {
+		if session.isClosed() { // 被 New 关闭的包默认会话主体
+			session = NewSession("", cfg.SessionTimeOut)
+		}
+		config.Config, aiclient, DefaultSession = cfg, client, session
+	})
+}
+
+func TestNew(t *testing.T) {
+	restoreGlobals(t)
+	dir := t.TempDir()
+	cfgFile := filepath.Join(dir, "ai.yaml")
+	data := []byte("model: gpt-4.1-mini\nconfigs:\n  - api_url: http://127.0.0.1:1/v1/chat/completions\n    authorization_list: [sk-file]\n")
+	if err := os.WriteFile(cfgFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantModel  string
+		wantSystem string
+		wantErr    error
+	}{
+		{name: "config", opts: []Option{WithConfig(testSDKConfig())}, wantModel: config.DefaultModel},
+		{name: "config file", opts: []Option{WithConfigFile(cfgFile), WithSystemPrompt("人设")}, wantModel: "gpt-4.1-mini", wantSystem: "人设"},
+		{name: "later option wins", opts: []Option{WithConfigFile(cfgFile), WithConfig(testSDKConfig())}, wantModel: config.DefaultModel},
+		{name: "missing file", opts: []Option{WithConfigFile(filepath.Join(dir, "missing.yaml"))}, wantErr: os.ErrNotExist},
+		{name: "defaults without key", wantErr: clientOptionErr},
+		{name: "invalid client option", opts: []Option{WithConfig(testSDKConfig()), WithClientOptions(WithTimeout(-time.Second))}, wantErr: clientOptionErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sdk, err := New(tt.opts...)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("New() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer sdk.Session.Close()
+			if sdk.Client.Model != tt.wantModel || sdk.Config.Model != tt.wantModel {
+				t.Errorf("model = %s / %s, want %s", sdk.Client.Model, sdk.Config.Model, tt.wantModel)
+			}
+			if aiclient != sdk.Client || DefaultSession != sdk.Session {
+				t.Error("New() should install the created client and session as globals")
+			}
+			info := sdk.Session.GetSession("new", nil)
+			if info.aiClient() != sdk.Client {
+				t.Error("session should use the SDK client")
+			}
+			msgs := info.history.getMessage()
+			if tt.wantSystem == "" && len(msgs) != 0 || tt.wantSystem != "" && (len(msgs) != 1 || msgs[0].Content != tt.wantSystem) {
+				t.Errorf("system messages = %+v, want %q", msgs, tt.wantSystem)
+			}
+		})
+	}
+	if got, _ := os.ReadFile(cfgFile); string(got) != string(data) {
+		t.Error("WithConfigFile should not rewrite the config file")
+	}
+}
+
+func TestNew_clientOptions(t *testing.T) {
+	restoreGlobals(t)
+	var notified []KeyStatus
+	sdk, err := New(WithConfig(testSDKConfig()), WithClientOptions(
+		WithKeyQuarantineHandler(func(status KeyStatus) { notified = append(notified, status) }),
+		WithUserAgent("test-bot/1.0"),
+	))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer sdk.Close()
+	if got := sdk.Client.header.Get(userAgentHeader); got != "test-bot/1.0" {
+		t.Errorf("User-Agent = %q, want test-bot/1.0", got)
+	}
+	sdk.Client.quarantineKey(0, 0, sdk.Client.endpointAt(0).key(0), QuarantineUnauthorized, "")
+	if len(notified) != 1 || notified[0].Reason != QuarantineUnauthorized {
+		t.Errorf("notified = %+v, want one unauthorized status", notified)
+	}
+}
+
+func TestNew_replacesDefaultSession(t *testing.T) {
+	restoreGlobals(t)
+	initial := NewSession("", 2)
+	initial.GetSession("leak", nil) // 启动存活计时 goroutine
+	DefaultSession = initial
+	first, err := New(WithConfig(testSDKConfig()))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer first.Close()
+	if !initial.isClosed() {
+		t.Error("New() should close the replaced package default session")
+	}
+	second, err := New(WithConfig(testSDKConfig()))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer second.Close()
+	if first.Session.isClosed() {
+		t.Error("New() should not close a session owned by another SDK")
+	}
+	first.Close()
+	if !first.Session.isClosed() {
+		t.Error("SDK.Close() should close its session")
+	}
+}
+
+func TestNew_isolatedConfig(t *testing.T) {
+	restoreGlobals(t)
+	global := config.Config
+	newSDK := func(historyNum, maxToolRounds, maxSessions, contextWindow int) *SDK {
+		cfg := testSDKConfig()
+		cfg.HistoryNum, cfg.MaxToolRounds, cfg.MaxSessions, cfg.ContextWindow = historyNum, maxToolRounds, maxSessions, contextWindow
+		sdk, err := New(WithConfig(cfg))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		t.Cleanup(sdk.Close)
+		return sdk
+	}
+	first := newSDK(3, 2, 10, 4096)
+	second := newSDK(7, 4, 20, 8192)
+	if config.Config.HistoryNum != global.HistoryNum || config.Config.MaxToolRounds != global.MaxToolRounds {
+		t.Errorf("New() should not change config.Config: %+v", config.Config)
+	}
+	for _, tt := range []struct {
+		sdk                                *SDK
+		historyNum, maxRounds, maxSessions int
+		window                             int
+	}{
+		{first, 3, 2, 10, 4096},
+		{second, 7, 4, 20, 8192},
+	} {
+		info := tt.sdk.Session.GetSession("isolated", nil)
+		maxRounds, _ := tt.sdk.Session.toolLoopLimit()
+		if info.history.maxHistory != tt.historyNum || maxRounds != tt.maxRounds || tt.sdk.Session.maxSessions != tt.maxSessions {
+			t.Errorf("limits = %d, %d, %d, want %d, %d, %d", info.history.maxHistory, maxRounds, tt.sdk.Session.maxSessions, tt.historyNum, tt.maxRounds, tt.maxSessions)
+		}
+		if got := info.tokenBudget() + defaultReplyReserve + replyTokenOverhead; got != tt.window {
+			t.Errorf("context window = %d, want %d", got, tt.window)
+		}
+	}
+}
+
+func TestConfigSetup(t *testing.T) {
+	restoreGlobals(t)
+	dir := t.TempDir()
+	if err := config.Setup(dir); err != nil {
+		t.Fatalf("config.Setup() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, config.DefaultFileName))
+	if err != nil || !strings.Contains(string(data), "authorization_list") {
+		t.Fatalf("config template = %s, %v", data, err)
+	}
+	if len(config.Config.ApiCfgs) != 1 || len(config.Config.ApiCfgs[0].AuthList) == 0 {
+		t.Errorf("config.Config = %+v, want example api config", config.Config)
+	}
+}
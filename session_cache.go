@@ -0,0 +1,115 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午1:40:00
+// @Desc 会话缓存容量限制：会话数超出上限时按 LRU 移除，单个会话问答超出字节上限时移除最早的问答
+package ai_sdk
+
+// EvictReason 会话或问答被自动移除的原因
+type EvictReason string
+
+const (
+	EvictCapacity     EvictReason = "capacity"      // 会话数超出上限，移除最久未使用的会话 (持久化数据保留)
+	EvictExpired      EvictReason = "expired"       // 会话超时
+	EvictMessageBytes EvictReason = "message_bytes" // 会话问答记录超出字节上限，移除最早的问答
+)
+
+// EvictionFunc 自动移除回调，record 为被移除的内容：移除会话时为完整会话，移除问答时仅包含被移除的问答
+// 回调在会话锁外执行，可以调用 Session 的方法；通过 Session.Delete / Close 主动移除时不会回调
+type EvictionFunc func(reason EvictReason, record SessionRecord)
+
+// SetCapacity 设置内存中最多保留的会话数与单个会话问答记录的最大字节数，传入 0 表示不限制
+// 会话数上限立即生效，字节数上限对之后新建的会话生效
+func (s *Session) SetCapacity(maxSessions int, maxSessionBytes int) {
+	s.mu.Lock()
+	s.maxSessions = maxSessions
+	s.maxSessionBytes = maxSessionBytes
+	evicted := s.evictOverflow()
+	s.mu.Unlock()
+	for _, info := range evicted {
+		s.notifyEvict(EvictCapacity, info)
+	}
+}
+
+// OnEvict 设置自动移除回调，对之后新建的会话中被移除的问答同样生效，nil 表示不回调
+func (s *Session) OnEvict(fn EvictionFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onEvict = fn
+}
+
+// Len 内存中的会话数
+func (s *Session) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.cache)
+}
+
+// evictOverflow 移除超出会话数上限的最久未使用会话并停止其计时器 concurrent unsafe (需持有 s.mu)
+// 持久化数据保留，再次获取该会话时从存储中恢复
+func (s *Session) evictOverflow() (evicted []*sessionInfo) {
+	for s.maxSessions > 0 && len(s.cache) > s.maxSessions {
+		info := s.lru.Back().Value.(*sessionInfo)
+		s.uncache(info)
+		info.stop()
+		evicted = append(evicted, info)
+	}
+	return evicted
+}
+
+// uncache 从缓存中移除会话 concurrent unsafe (需持有 s.mu)
+func (s *Session) uncache(info *sessionInfo) {
+	delete(s.cache, info.sessionId)
+	if info.elem != nil {
+		s.lru.Remove(info.elem)
+		info.elem = nil
+	}
+}
+
+// notifyEvict 回调被移除的会话，需在 s.mu 锁外调用
+func (s *Session) notifyEvict(reason EvictReason, info *sessionInfo) {
+	s.mu.RLock()
+	fn := s.onEvict
+	s.mu.RUnlock()
+	if fn == nil {
+		return
+	}
+	info.history.mu.Lock()
+	record := info.history.record()
+	info.history.mu.Unlock()
+	fn(reason, record)
+}
+
+// trimBytes 移除最早的问答直到问答记录不超过字节上限，最近一轮问答始终保留 concurrent unsafe (需持有 h.mu)
+func (h *history) trimBytes() (dropped []dialogEntry) {
+	if h.maxBytes <= 0 {
+		return nil
+	}
+	total := 0
+	for _, entry := range h.dialog {
+		total += entry.bytes
+	}
+	for len(h.dialog) > 1 && total > h.maxBytes {
+		entry := h.removeFirst()
+		total -= entry.bytes
+		dropped = append(dropped, entry)
+	}
+	return dropped
+}
+
+// entryBytes 计算一轮问答的字节数
+func entryBytes(entry dialogEntry) int {
+	n := messageBytes(entry.question)
+	for _, answer := range entry.answerList {
+		n += messageBytes(answer)
+	}
+	return n
+}
+
+// messageBytes 计算单条消息内容的字节数
+func messageBytes(msg Message) int {
+	n := len(msg.Content) + len(msg.Refusal)
+	for _, call := range msg.ToolCalls {
+		n += len(call.Function.Name) + len(call.Function.Arguments)
+	}
+	return n
+}
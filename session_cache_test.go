@@ -0,0 +1,92 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午2:00:00
+// @Desc 会话缓存容量限制测试
+package ai_sdk
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+type evictLog struct {
+	mu      sync.Mutex
+	reasons []EvictReason
+	records []SessionRecord
+}
+
+func (l *evictLog) onEvict(reason EvictReason, record SessionRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reasons = append(l.reasons, reason)
+	l.records = append(l.records, record)
+}
+
+func TestSession_SetCapacity(t *testing.T) {
+	base := runtime.NumGoroutine()
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSession("预设", 2)
+	s.SetStore(store)
+	var evicted evictLog
+	s.OnEvict(evicted.onEvict)
+	s.SetCapacity(3, 0)
+
+	for _, id := range []string{"a", "b", "c"} {
+		s.GetSession(id, nil)
+	}
+	s.GetSession("a", nil).history.addLast(dialogEntry{question: Message{Role: userRole, Content: "记住我"}})
+	s.GetSession("d", nil) // b 最久未使用
+	if s.Len() != 3 || s.IsExist("b") || !s.IsExist("a") {
+		t.Fatalf("after eviction Len() = %d, b exists = %v", s.Len(), s.IsExist("b"))
+	}
+	s.SetCapacity(1, 0) // 立即生效：依次移除 c、a
+	if s.Len() != 1 || !s.IsExist("d") {
+		t.Fatalf("after shrinking Len() = %d", s.Len())
+	}
+	var ids []string
+	for i, reason := range evicted.reasons {
+		if reason != EvictCapacity {
+			t.Errorf("reason = %s, want %s", reason, EvictCapacity)
+		}
+		ids = append(ids, evicted.records[i].SessionId)
+	}
+	if len(ids) != 3 || ids[0] != "b" || ids[1] != "c" || ids[2] != "a" {
+		t.Errorf("evicted sessions = %v, want [b c a]", ids)
+	}
+	// 按容量移除的会话保留持久化数据，再次获取时恢复
+	if msgs := s.GetSession("a", nil).history.getMessage(); len(msgs) != 2 || msgs[1].Content != "记住我" {
+		t.Errorf("restored history = %+v", msgs)
+	}
+	s.Close()
+	waitGoroutines(t, base)
+}
+
+func Test_history_trimBytes(t *testing.T) {
+	var evicted evictLog
+	h := &history{maxBytes: 10, sessionId: "bytes", countTokens: estimateMessageTokens, onEvict: evicted.onEvict}
+	entry := func(question, answer string) dialogEntry {
+		return dialogEntry{question: Message{Role: userRole, Content: question}, answerList: answerList{{Role: assistantRole, Content: answer}}}
+	}
+	h.addLast(entry("你好", "好")) // 9 bytes
+	if len(evicted.reasons) != 0 {
+		t.Fatalf("unexpected eviction: %+v", evicted.records)
+	}
+	h.addLast(entry("在吗", "在"))           // 18 bytes > 10
+	h.addLast(entry("这一轮问答单独就超过了上限", "")) // 最近一轮始终保留
+	if len(h.dialog) != 1 || h.dialog[0].question.Content != "这一轮问答单独就超过了上限" {
+		t.Fatalf("dialog = %+v", h.dialog)
+	}
+	if len(evicted.records) != 2 || evicted.reasons[0] != EvictMessageBytes {
+		t.Fatalf("evicted = %+v", evicted.records)
+	}
+	for i, want := range []string{"你好", "在吗"} {
+		record := evicted.records[i]
+		if record.SessionId != "bytes" || len(record.Dialogs) != 1 || record.Dialogs[0].Question.Content != want {
+			t.Errorf("evicted record %d = %+v, want question %q", i, record, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午1:00:00
+// @Desc 会话生命周期：删除、重置会话与关闭会话主体，确保存活计时 goroutine 全部退出
+package ai_sdk
+
+// Delete 删除会话：停止存活计时并删除持久化的会话数据，会话不存在时返回 false
+func (s *Session) Delete(sessionId string) bool {
+	return s.removeById(sessionId)
+}
+
+// Reset 清空会话的问答记录与摘要，保留会话与预设并刷新存活时间，会话不存在时返回 false
+func (s *Session) Reset(sessionId string) bool {
+	s.mu.RLock()
+	info, ok := s.cache[sessionId]
+	s.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	info.history.reset()
+	info.keepAlive()
+	return true
+}
+
+// Close 关闭会话主体：停止全部会话的存活计时并等待其退出，持久化的会话数据保留，以便重启后恢复
+// 关闭后不再创建会话，已获取的会话对话时返回错误；SessionStore 由调用方自行关闭
+func (s *Session) Close() {
+	s.mu.Lock()
+	s.closed = true
+	infos := s.cache
+	s.cache = make(map[string]*sessionInfo)
+	s.lru.Init()
+	s.mu.Unlock()
+	for _, info := range infos {
+		info.stop()
+	}
+	s.wg.Wait()
+}
+
+// isClosed 会话主体是否已关闭
+func (s *Session) isClosed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closed
+}
+
+// expire 将超时的会话移出内存，持久化的会话数据保留 (仅 Delete 时删除)，会话已被删除或替换时不做处理
+func (s *Session) expire(info *sessionInfo) {
+	s.mu.Lock()
+	if s.cache[info.sessionId] != info {
+		s.mu.Unlock()
+		return
+	}
+	s.uncache(info)
+	s.mu.Unlock()
+	info.stop()
+	s.notifyEvict(EvictExpired, info)
+}
+
+// keepAlive 刷新会话存活时间，不阻塞：已有未处理的信号或会话已结束时直接返回
+func (s *sessionInfo) keepAlive() {
+	select {
+	case s.survivalSignal <- struct{}{}:
+	default:
+	}
+}
+
+// stop 结束会话的存活计时，可重复调用
+func (s *sessionInfo) stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// isClosed 会话是否已结束 (被删除、过期、淘汰) 或所属的会话主体已关闭，结束后需通过 GetSession 重新获取
+func (s *sessionInfo) isClosed() bool {
+	return s.history.stopped() || s.session != nil && s.session.isClosed()
+}
+
+// reset 清空问答记录与摘要
+func (h *history) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dialog = nil
+	h.summary = ""
+	h.pending = nil
+	h.save()
+}
@@ -0,0 +1,154 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午1:20:00
+// @Desc 会话生命周期测试：通过 goroutine 计数确认计时器全部退出
+package ai_sdk
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitGoroutines 等待 goroutine 数回落到 want 以内
+func waitGoroutines(t *testing.T, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > want {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("goroutine leak: %d > %d\n%s", runtime.NumGoroutine(), want, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSession_lifecycle(t *testing.T) {
+	const sessions = 20
+	tests := []struct {
+		name    string
+		limit   time.Duration
+		release func(s *Session)
+	}{
+		{name: "close", limit: time.Minute, release: func(s *Session) { s.Close() }},
+		{name: "delete", limit: time.Minute, release: func(s *Session) {
+			for i := 0; i < sessions; i++ {
+				if !s.Delete(fmt.Sprint(i)) {
+					t.Errorf("Delete(%d) = false", i)
+				}
+			}
+			if s.Delete("missing") {
+				t.Error("Delete(missing) = true")
+			}
+		}},
+		{name: "expire", limit: 50 * time.Millisecond, release: func(s *Session) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := runtime.NumGoroutine()
+			s := NewSession("预设", 2)
+			s.globalSurvivalLimit = tt.limit
+			infos := make([]*sessionInfo, sessions)
+			for i := range infos {
+				infos[i] = s.GetSession(fmt.Sprint(i), nil)
+			}
+			if n := runtime.NumGoroutine(); n < base+sessions {
+				t.Fatalf("expected one timer goroutine per session, got %d -> %d", base, n)
+			}
+			tt.release(s)
+			waitGoroutines(t, base)
+			for _, info := range infos { // 会话结束后刷新存活时间不阻塞也不产生 goroutine
+				info.keepAlive()
+				info.keepAlive()
+			}
+			waitGoroutines(t, base)
+			for i := 0; i < sessions; i++ {
+				if s.IsExist(fmt.Sprint(i)) {
+					t.Errorf("session %d still exists", i)
+				}
+			}
+		})
+	}
+}
+
+func TestSession_Reset(t *testing.T) {
+	s := NewSession("预设", 2)
+	defer s.Close()
+	info := s.GetSession("reset", nil)
+	info.history.addLast(dialogEntry{question: Message{Role: userRole, Content: "你好"}, answerList: answerList{{Role: assistantRole, Content: "你好呀"}}})
+	info.history.summary = "摘要"
+	if !s.Reset("reset") || s.Reset("missing") {
+		t.Fatal("Reset() result mismatch")
+	}
+	msgs := s.GetSession("reset", nil).history.getMessage()
+	if len(msgs) != 1 || msgs[0].Role != systemRole {
+		t.Errorf("history after Reset() = %+v, want only system", msgs)
+	}
+}
+
+func TestSession_Close(t *testing.T) {
+	base := runtime.NumGoroutine()
+	s := NewSession("预设", 2)
+	s.GetSession("before", nil)
+	s.Close()
+	s.Close() // 可重复关闭
+	info := s.GetSession("after", nil)
+	if _, err := info.Talk("你好"); !errors.Is(err, sessionClosedErr) {
+		t.Errorf("Talk() after Close error = %v, want sessionClosedErr", err)
+	}
+	if s.IsExist("after") {
+		t.Error("session created after Close should not be cached")
+	}
+	waitGoroutines(t, base)
+}
+
+func TestSession_DeleteStaleHandle(t *testing.T) {
+	started, deleted := make(chan struct{}), make(chan struct{})
+	server := newJSONServer(t, []string{"第一轮", "进行中"}, func(round int, req ChatCompletionRequest) {
+		if round == 1 { // 第二轮对话进行中时删除会话
+			close(started)
+			<-deleted
+		}
+	})
+	defer server.Close()
+	old := aiclient
+	aiclient = newTestClient(server.URL)
+	defer func() { aiclient = old }()
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSessionStore() error = %v", err)
+	}
+	s := NewSession("预设", 2)
+	defer s.Close()
+	s.SetStore(store)
+	info := s.GetSession("stale", nil)
+	if _, err = info.Talk("问题一"); err != nil {
+		t.Fatalf("Talk() error = %v", err)
+	}
+
+	talked := make(chan error, 1)
+	go func() {
+		_, err := info.Talk("问题二")
+		talked <- err
+	}()
+	<-started
+	if !s.Delete("stale") {
+		t.Fatal("Delete() = false")
+	}
+	close(deleted)
+	if err = <-talked; err != nil {
+		t.Fatalf("in-flight Talk() error = %v", err)
+	}
+	if _, err = info.Talk("问题三"); !errors.Is(err, sessionClosedErr) {
+		t.Errorf("Talk() on deleted session error = %v, want sessionClosedErr", err)
+	}
+	info.history.reset()
+	if record, ok, err := store.Load("stale"); ok || err != nil {
+		t.Errorf("Load() after Delete = %+v, %v, %v, want no record", record, ok, err)
+	}
+	if fresh := s.GetSession("stale", nil); fresh == info || len(fresh.history.getMessage()) != 1 {
+		t.Errorf("GetSession() after Delete should create a new session with only the system message")
+	}
+}
@@ -0,0 +1,95 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午2:30:00
+// @Desc 会话级配置：在创建会话时为单个会话指定模型、预设、生成参数、可用工具与客户端
+package ai_sdk
+
+import "github.com/Clov614/go-ai-sdk/config"
+
+// SessionOption 会话级配置，通过 Session.GetSession 在创建会话时传入
+type SessionOption func(o *sessionOptions)
+
+type sessionOptions struct {
+	model        string
+	system       *string
+	params       config.ChatParams
+	allowedTools map[string]bool
+	client       *AIClient
+}
+
+// WithSessionModel 会话使用的模型，覆盖客户端的模型
+func WithSessionModel(model string) SessionOption {
+	return func(o *sessionOptions) {
+		o.model = model
+	}
+}
+
+// WithSessionSystem 会话预设，替换 Session 的预设 (extraOp 的额外信息仍会拼接在后面)
+func WithSessionSystem(system string) SessionOption {
+	return func(o *sessionOptions) {
+		o.system = &system
+	}
+}
+
+// WithSessionParams 会话生成参数，覆盖 Session.SetParams 中的同名参数，可被单次请求参数覆盖
+func WithSessionParams(params config.ChatParams) SessionOption {
+	return func(o *sessionOptions) {
+		o.params = params
+	}
+}
+
+// WithSessionTools 会话允许使用的工具名，仅这些工具会随请求发送，模型请求其它工具时返回错误信息；不传工具名表示禁用全部工具
+func WithSessionTools(names ...string) SessionOption {
+	return func(o *sessionOptions) {
+		o.allowedTools = make(map[string]bool, len(names))
+		for _, name := range names {
+			o.allowedTools[name] = true
+		}
+	}
+}
+
+// WithSessionClient 会话使用的客户端 (如不同的 API 密钥或节点)，nil 表示使用全局客户端
+func WithSessionClient(client *AIClient) SessionOption {
+	return func(o *sessionOptions) {
+		o.client = client
+	}
+}
+
+// aiClient 会话使用的客户端：会话级客户端 > 会话主体的客户端 > 全局客户端
+func (s *sessionInfo) aiClient() *AIClient {
+	if s.client != nil {
+		return s.client
+	}
+	if s.session != nil && s.session.client != nil {
+		return s.session.client
+	}
+	return aiclient
+}
+
+// modelName 会话使用的模型
+func (s *sessionInfo) modelName() string {
+	if s.model != "" {
+		return s.model
+	}
+	return s.aiClient().Model
+}
+
+// toolAllowed 会话是否允许使用该工具
+func (s *sessionInfo) toolAllowed(name string) bool {
+	return s.allowedTools == nil || s.allowedTools[name]
+}
+
+// selectTools 根据问题选择工具，并过滤掉会话不允许使用的工具
+func (s *sessionInfo) selectTools(content string) *[]Tool {
+	tools := FuncRegister.GetToolsByContent(content)
+	if s.allowedTools == nil || tools == nil {
+		return tools
+	}
+	allowed := make([]Tool, 0, len(*tools))
+	for _, tool := range *tools {
+		if s.toolAllowed(tool.Function.Name) {
+			allowed = append(allowed, tool)
+		}
+	}
+	return &allowed
+}
@@ -0,0 +1,84 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午2:50:00
+// @Desc 会话级配置测试
+package ai_sdk
+
+import (
+	"github.com/Clov614/go-ai-sdk/config"
+	"github.com/Clov614/go-ai-sdk/global"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func toolNames(tools *[]Tool) (names []string) {
+	if tools == nil {
+		return nil
+	}
+	for _, tool := range *tools {
+		names = append(names, tool.Function.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSession_GetSessionOptions(t *testing.T) {
+	for _, name := range []string{"opt_tool_a", "opt_tool_b"} {
+		if err := FuncRegister.Register(&FuncCallInfo{
+			Function: Function{Name: name, Parameters: FunctionParameter{Type: global.ObjType}},
+			CallFunc: echoCallFunc{},
+		}, []string{"opt_tools"}); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+	defaultServer := newJSONServer(t, []string{"普通回答"}, func(round int, req ChatCompletionRequest) {
+		if req.Model != config.DefaultModel || req.Messages[0].Content != "默认预设" || req.Temperature != nil {
+			t.Errorf("default session request = %+v", req)
+		}
+		if names := toolNames(req.Tools); strings.Join(names, ",") != "opt_tool_a,opt_tool_b" {
+			t.Errorf("default session tools = %v", names)
+		}
+	})
+	defer defaultServer.Close()
+	vipServer := newChoiceServer(t, []Choice{
+		{Message: Message{Role: assistantRole, ToolCalls: []ToolCall{{ID: "call_1", Type: defaultFuncType, Function: FunctionCall{Name: "opt_tool_b", Arguments: "{}"}}}}, FinishReason: ToolsCallFinishReason},
+		{Message: Message{Role: assistantRole, Content: "VIP 回答"}, FinishReason: "stop"},
+	}, func(round int, req ChatCompletionRequest) {
+		if req.Model != "gpt-4o" || req.Messages[0].Content != "VIP 预设\n额外信息" {
+			t.Errorf("vip session request = %+v", req)
+		}
+		if req.Temperature == nil || *req.Temperature != 0.2 {
+			t.Errorf("vip session temperature = %v", req.Temperature)
+		}
+		if names := toolNames(req.Tools); strings.Join(names, ",") != "opt_tool_a" {
+			t.Errorf("vip session tools = %v", names)
+		}
+		if last := req.Messages[len(req.Messages)-1]; round == 1 && !strings.Contains(last.Content, toolNotAllowedErr.Error()) {
+			t.Errorf("disallowed tool reply = %+v", last)
+		}
+	})
+	defer vipServer.Close()
+	old := aiclient
+	aiclient = newTestClient(defaultServer.URL)
+	defer func() { aiclient = old }()
+
+	s := NewSession("默认预设", 2)
+	defer s.Close()
+	vip := s.GetSession("vip", func() string { return "额外信息" },
+		WithSessionModel("gpt-4o"),
+		WithSessionSystem("VIP 预设"),
+		WithSessionParams(config.ChatParams{Temperature: Ptr(0.2)}),
+		WithSessionTools("opt_tool_a"),
+		WithSessionClient(newTestClient(vipServer.URL)),
+	)
+	if got, err := vip.Talk("opt_tools"); err != nil || got != "VIP 回答" {
+		t.Errorf("vip Talk() = %q, %v", got, err)
+	}
+	if got, err := s.TalkById("free", "opt_tools"); err != nil || got != "普通回答" {
+		t.Errorf("default TalkById() = %q, %v", got, err)
+	}
+	if s.GetSession("vip", nil, WithSessionModel("ignored")).model != "gpt-4o" {
+		t.Error("options should only apply when the session is created")
+	}
+}
@@ -0,0 +1,208 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午8:40:00
+// @Desc 会话持久化：SessionStore 接口与按会话存储 JSON 文件的实现
+package ai_sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sessionFileExt = ".json"
+
+var storeClosedErr = errors.New("session store closed") // 存储已关闭
+
+// SessionStore 会话持久化存储，Session 在创建会话时加载、每轮对话后保存、会话移除时删除
+// 实现需保证并发安全
+type SessionStore interface {
+	Load(sessionId string) (record SessionRecord, ok bool, err error) // 会话不存在时 ok 为 false
+	Save(record SessionRecord) error
+	Delete(sessionId string) error // 会话不存在时不返回错误
+	List() (sessionIds []string, err error)
+}
+
+// SessionRecord 持久化的会话数据
+type SessionRecord struct {
+	SessionId string         `json:"session_id"`
+	System    Message        `json:"system"`            // 会话预设
+	Summary   string         `json:"summary,omitempty"` // 被移出问答的滚动摘要
+	Dialogs   []DialogRecord `json:"dialogs"`           // 问答记录，按时间先后排列
+	StartTime time.Time      `json:"start_time"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// DialogRecord 一轮问答：用户问题与本轮产生的全部回答 (含工具调用与工具结果)
+type DialogRecord struct {
+	Question Message   `json:"question"`
+	Answers  []Message `json:"answers"`
+}
+
+// SetStore 设置会话持久化存储，设置后新建的会话会优先从存储中恢复，nil 表示不持久化
+func (s *Session) SetStore(store SessionStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// Store 获取会话持久化存储
+func (s *Session) Store() SessionStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.store
+}
+
+// record 将会话转换为持久化数据 concurrent unsafe (需持有 h.mu)
+func (h *history) record() SessionRecord {
+	record := SessionRecord{
+		SessionId: h.sessionId,
+		System:    h.system,
+		Summary:   h.summary,
+		Dialogs:   dialogRecords(h.dialog),
+		StartTime: h.startTime,
+		UpdatedAt: time.Now(),
+	}
+	return record
+}
+
+// dialogRecords 将问答转换为持久化数据
+func dialogRecords(entries []dialogEntry) []DialogRecord {
+	records := make([]DialogRecord, len(entries))
+	for i, entry := range entries {
+		records[i] = DialogRecord{Question: entry.question, Answers: entry.answerList}
+	}
+	return records
+}
+
+// restore 从持久化数据恢复问答记录
+func (h *history) restore(record SessionRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.system = record.System
+	h.summary = record.Summary
+	h.startTime = record.StartTime
+	h.dialog = make([]dialogEntry, 0, len(record.Dialogs))
+	for _, d := range record.Dialogs {
+		entry := dialogEntry{question: d.Question, answerList: d.Answers}
+		entry.tokens = h.entryTokens(entry)
+		entry.bytes = entryBytes(entry)
+		h.dialog = append(h.dialog, entry)
+	}
+	for h.maxHistory > 0 && len(h.dialog) > h.maxHistory {
+		h.removeFirst()
+	}
+	h.trimBytes()
+}
+
+// save 保存会话 concurrent unsafe (需持有 h.mu)，会话已结束 (被删除、过期或淘汰) 时不再保存，避免覆盖删除或新的会话
+func (h *history) save() {
+	if h.store == nil || h.stopped() {
+		return
+	}
+	if err := h.store.Save(h.record()); err != nil {
+		log.Error().Err(err).Str("sessionId", h.sessionId).Msg("save session failed")
+	}
+}
+
+// stopped 会话是否已结束
+func (h *history) stopped() bool {
+	select {
+	case <-h.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// FileSessionStore 每个会话保存为目录下的一个 JSON 文件，文件名为转义后的会话id，便于离线查看
+type FileSessionStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileSessionStore 创建 JSON 文件存储，目录不存在时自动创建
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("NewFileSessionStore mkdir %s: %w", dir, err)
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+func (f *FileSessionStore) path(sessionId string) string {
+	return filepath.Join(f.dir, url.PathEscape(sessionId)+sessionFileExt)
+}
+
+func (f *FileSessionStore) Load(sessionId string) (record SessionRecord, ok bool, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := os.ReadFile(f.path(sessionId))
+	if errors.Is(err, os.ErrNotExist) {
+		return record, false, nil
+	}
+	if err != nil {
+		return record, false, fmt.Errorf("FileSessionStore.Load %s: %w", sessionId, err)
+	}
+	if err = json.Unmarshal(data, &record); err != nil {
+		return record, false, fmt.Errorf("FileSessionStore.Load %s unmarshal: %w", sessionId, err)
+	}
+	return record, true, nil
+}
+
+// Save 先写入临时文件再重命名，避免进程中断时留下不完整的文件
+func (f *FileSessionStore) Save(record SessionRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("FileSessionStore.Save %s marshal: %w", record.SessionId, err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := f.path(record.SessionId)
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("FileSessionStore.Save %s: %w", record.SessionId, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("FileSessionStore.Save %s rename: %w", record.SessionId, err)
+	}
+	return nil
+}
+
+func (f *FileSessionStore) Delete(sessionId string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.Remove(f.path(sessionId)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("FileSessionStore.Delete %s: %w", sessionId, err)
+	}
+	return nil
+}
+
+func (f *FileSessionStore) List() (sessionIds []string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("FileSessionStore.List: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, sessionFileExt) {
+			continue
+		}
+		sessionId, err := url.PathUnescape(strings.TrimSuffix(name, sessionFileExt))
+		if err != nil {
+			continue // 非本存储写入的文件
+		}
+		sessionIds = append(sessionIds, sessionId)
+	}
+	sort.Strings(sessionIds)
+	return sessionIds, nil
+}
@@ -0,0 +1,216 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午9:00:00
+// @Desc 会话持久化：嵌入式键值文件存储 (追加写日志 + 内存索引 + 自动压缩)
+package ai_sdk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+const kvCompactMinGarbage = 64 // 失效记录数超过该值且多于有效记录时压缩日志
+
+// KVSessionStore 嵌入式键值文件存储：所有会话以 JSON 行追加写入同一个文件，打开时回放日志重建内存索引
+// 被覆盖或删除的记录过多时自动重写文件；写入不做 fsync，进程崩溃时尾部不完整的记录会在下次打开时丢弃，损坏的记录会被跳过
+type KVSessionStore struct {
+	path    string
+	file    *os.File
+	data    map[string]json.RawMessage // 会话id -> SessionRecord
+	garbage int                        // 日志中已失效的记录数
+	closed  bool
+	mu      sync.Mutex
+}
+
+// kvEntry 日志中的一条记录
+type kvEntry struct {
+	Key     string          `json:"k"`
+	Value   json.RawMessage `json:"v,omitempty"`
+	Deleted bool            `json:"d,omitempty"`
+}
+
+// NewKVSessionStore 打开 (不存在时创建) 键值文件存储，使用完毕后需调用 Close
+func NewKVSessionStore(path string) (*KVSessionStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("NewKVSessionStore open %s: %w", path, err)
+	}
+	kv := &KVSessionStore{path: path, file: file, data: make(map[string]json.RawMessage)}
+	if err = kv.replay(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("NewKVSessionStore %s: %w", path, err)
+	}
+	return kv, nil
+}
+
+// replay 回放日志重建索引：无法解析的记录跳过并计为失效记录 (压缩时清除)，仅截断尾部缺少换行的不完整记录
+func (kv *KVSessionStore) replay() error {
+	reader := bufio.NewReader(kv.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) == 0 {
+				return nil
+			}
+			break // 写入中断留下的不完整记录
+		}
+		if err != nil {
+			return fmt.Errorf("replay read: %w", err)
+		}
+		offset += int64(len(line))
+		var entry kvEntry
+		if err = json.Unmarshal(bytes.TrimSpace(line), &entry); err != nil {
+			log.Warn().Err(err).Str("path", kv.path).Int64("offset", offset-int64(len(line))).Msg("kv session store skipped corrupted record")
+			kv.garbage++
+			continue
+		}
+		if _, ok := kv.data[entry.Key]; ok {
+			kv.garbage++
+		}
+		if entry.Deleted {
+			delete(kv.data, entry.Key)
+			kv.garbage++
+			continue
+		}
+		kv.data[entry.Key] = entry.Value
+	}
+	log.Warn().Str("path", kv.path).Int64("offset", offset).Msg("kv session store truncated incomplete trailing record")
+	if err := kv.file.Truncate(offset); err != nil {
+		return fmt.Errorf("replay truncate: %w", err)
+	}
+	if _, err := kv.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("replay seek: %w", err)
+	}
+	return nil
+}
+
+func (kv *KVSessionStore) Load(sessionId string) (record SessionRecord, ok bool, err error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.closed {
+		return record, false, storeClosedErr
+	}
+	value, ok := kv.data[sessionId]
+	if !ok {
+		return record, false, nil
+	}
+	if err = json.Unmarshal(value, &record); err != nil {
+		return record, false, fmt.Errorf("KVSessionStore.Load %s unmarshal: %w", sessionId, err)
+	}
+	return record, true, nil
+}
+
+func (kv *KVSessionStore) Save(record SessionRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("KVSessionStore.Save %s marshal: %w", record.SessionId, err)
+	}
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if err = kv.append(kvEntry{Key: record.SessionId, Value: value}); err != nil {
+		return fmt.Errorf("KVSessionStore.Save %s: %w", record.SessionId, err)
+	}
+	if _, ok := kv.data[record.SessionId]; ok {
+		kv.garbage++
+	}
+	kv.data[record.SessionId] = value
+	return kv.compactIfNeeded()
+}
+
+func (kv *KVSessionStore) Delete(sessionId string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if _, ok := kv.data[sessionId]; !ok {
+		if kv.closed {
+			return storeClosedErr
+		}
+		return nil
+	}
+	if err := kv.append(kvEntry{Key: sessionId, Deleted: true}); err != nil {
+		return fmt.Errorf("KVSessionStore.Delete %s: %w", sessionId, err)
+	}
+	delete(kv.data, sessionId)
+	kv.garbage += 2 // 原记录与删除标记
+	return kv.compactIfNeeded()
+}
+
+func (kv *KVSessionStore) List() (sessionIds []string, err error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.closed {
+		return nil, storeClosedErr
+	}
+	sessionIds = make([]string, 0, len(kv.data))
+	for sessionId := range kv.data {
+		sessionIds = append(sessionIds, sessionId)
+	}
+	sort.Strings(sessionIds)
+	return sessionIds, nil
+}
+
+// Close 关闭存储文件，关闭后的读写均返回错误
+func (kv *KVSessionStore) Close() error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.closed {
+		return nil
+	}
+	kv.closed = true
+	return kv.file.Close()
+}
+
+// append 追加一条记录 concurrent unsafe (需持有 kv.mu)
+func (kv *KVSessionStore) append(entry kvEntry) error {
+	if kv.closed {
+		return storeClosedErr
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = kv.file.Write(append(line, '\n'))
+	return err
+}
+
+// compactIfNeeded 失效记录过多时只保留有效记录重写日志 concurrent unsafe (需持有 kv.mu)
+func (kv *KVSessionStore) compactIfNeeded() error {
+	if kv.garbage < kvCompactMinGarbage || kv.garbage <= len(kv.data) {
+		return nil
+	}
+	tmpPath := kv.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("compact open: %w", err)
+	}
+	writer := bufio.NewWriter(tmp)
+	for key, value := range kv.data {
+		line, _ := json.Marshal(kvEntry{Key: key, Value: value})
+		_, _ = writer.Write(append(line, '\n'))
+	}
+	if err = writer.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("compact write: %w", err)
+	}
+	if err = os.Rename(tmpPath, kv.path); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("compact rename: %w", err)
+	}
+	_ = kv.file.Close()
+	kv.file = tmp // 文件偏移位于末尾，可继续追加
+	kv.garbage = 0
+	return nil
+}
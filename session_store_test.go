@@ -0,0 +1,179 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午9:30:00
+// @Desc 会话持久化测试
+package ai_sdk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSessionStore(t *testing.T) {
+	dir := t.TempDir()
+	fileStore, err := NewFileSessionStore(filepath.Join(dir, "sessions"))
+	if err != nil {
+		t.Fatalf("NewFileSessionStore() error = %v", err)
+	}
+	kvStore, err := NewKVSessionStore(filepath.Join(dir, "sessions.kv"))
+	if err != nil {
+		t.Fatalf("NewKVSessionStore() error = %v", err)
+	}
+	defer kvStore.Close()
+
+	tests := []struct {
+		name  string
+		store SessionStore
+	}{
+		{name: "file", store: fileStore},
+		{name: "kv", store: kvStore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := SessionRecord{
+				SessionId: "group/1:user",
+				System:    Message{Role: systemRole, Content: "预设"},
+				Dialogs: []DialogRecord{{
+					Question: Message{Role: userRole, Content: "你好"},
+					Answers:  []Message{{Role: assistantRole, Content: "你好呀"}},
+				}},
+				StartTime: time.Date(2026, 10, 18, 21, 0, 0, 0, time.UTC),
+				UpdatedAt: time.Date(2026, 10, 18, 21, 1, 0, 0, time.UTC),
+			}
+			if _, ok, err := tt.store.Load(record.SessionId); ok || err != nil {
+				t.Fatalf("Load() missing = %v, %v", ok, err)
+			}
+			if err := tt.store.Save(record); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if err := tt.store.Save(SessionRecord{SessionId: "other"}); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			got, ok, err := tt.store.Load(record.SessionId)
+			if !ok || err != nil || !reflect.DeepEqual(got, record) {
+				t.Errorf("Load() = %+v, %v, %v, want %+v", got, ok, err, record)
+			}
+			if ids, err := tt.store.List(); err != nil || !reflect.DeepEqual(ids, []string{"group/1:user", "other"}) {
+				t.Errorf("List() = %v, %v", ids, err)
+			}
+			if err = tt.store.Delete(record.SessionId); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err = tt.store.Delete(record.SessionId); err != nil {
+				t.Errorf("Delete() missing error = %v", err)
+			}
+			if ids, _ := tt.store.List(); !reflect.DeepEqual(ids, []string{"other"}) {
+				t.Errorf("List() after delete = %v", ids)
+			}
+		})
+	}
+}
+
+func TestKVSessionStore_reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.kv")
+	kv, err := NewKVSessionStore(path)
+	if err != nil {
+		t.Fatalf("NewKVSessionStore() error = %v", err)
+	}
+	for i := 0; i < kvCompactMinGarbage*2; i++ { // 反复覆盖触发压缩
+		if err = kv.Save(SessionRecord{SessionId: fmt.Sprintf("s%d", i%2), Dialogs: []DialogRecord{{Question: Message{Content: fmt.Sprint(i)}}}}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if err = kv.Delete("s0"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if kv.garbage >= kvCompactMinGarbage {
+		t.Errorf("garbage = %d, want compacted", kv.garbage)
+	}
+	_ = kv.Close()
+	if _, _, err = kv.Load("s1"); err != storeClosedErr {
+		t.Errorf("Load() after Close error = %v, want storeClosedErr", err)
+	}
+
+	// 模拟中间损坏的记录与写入中断留下的不完整记录，损坏记录之后的有效记录不能丢失
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	_, _ = f.WriteString("{\"k\":\"s4\",\"v\":{\"sess\x00\n")
+	_, _ = f.WriteString(`{"k":"s5","v":{"session_id":"s5"}}` + "\n")
+	_, _ = f.WriteString(`{"k":"s2","v":{"sess`)
+	_ = f.Close()
+
+	kv, err = NewKVSessionStore(path)
+	if err != nil {
+		t.Fatalf("NewKVSessionStore() reopen error = %v", err)
+	}
+	defer kv.Close()
+	record, ok, err := kv.Load("s1")
+	if !ok || err != nil || record.Dialogs[0].Question.Content != fmt.Sprint(kvCompactMinGarbage*2-1) {
+		t.Errorf("Load() after reopen = %+v, %v, %v", record, ok, err)
+	}
+	if ids, _ := kv.List(); !reflect.DeepEqual(ids, []string{"s1", "s5"}) {
+		t.Errorf("List() after reopen = %v", ids)
+	}
+	if kv.garbage != 1 {
+		t.Errorf("garbage after reopen = %d, want the corrupted record counted", kv.garbage)
+	}
+	if err = kv.Save(SessionRecord{SessionId: "s3"}); err != nil {
+		t.Fatalf("Save() after truncate error = %v", err)
+	}
+	_ = kv.Close()
+	if kv, err = NewKVSessionStore(path); err != nil {
+		t.Fatalf("NewKVSessionStore() reopen error = %v", err)
+	}
+	defer kv.Close()
+	if ids, _ := kv.List(); !reflect.DeepEqual(ids, []string{"s1", "s3", "s5"}) {
+		t.Errorf("List() after second reopen = %v", ids)
+	}
+}
+
+func TestSession_SetStore(t *testing.T) {
+	server := newJSONServer(t, []string{"第一轮", "第二轮"}, nil)
+	defer server.Close()
+	old := aiclient
+	aiclient = newTestClient(server.URL)
+	defer func() { aiclient = old }()
+
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSessionStore() error = %v", err)
+	}
+	s := NewSession("持久化测试", 2)
+	s.SetStore(store)
+	if _, err = s.TalkById("persist", "问题一"); err != nil {
+		t.Fatalf("TalkById() error = %v", err)
+	}
+
+	// 模拟重启：新的会话主体从存储中恢复上下文
+	restarted := NewSession("持久化测试", 2)
+	restarted.SetStore(store)
+	if _, err = restarted.TalkById("persist", "问题二"); err != nil {
+		t.Fatalf("TalkById() error = %v", err)
+	}
+	record, ok, err := store.Load("persist")
+	if !ok || err != nil || len(record.Dialogs) != 2 || record.Dialogs[0].Answers[0].Content != "第一轮" {
+		t.Fatalf("Load() = %+v, %v, %v", record, ok, err)
+	}
+	msgs := restarted.GetSession("persist", nil).history.getMessage()
+	if len(msgs) != 5 || msgs[0].Content != "持久化测试" || msgs[4].Content != "第二轮" {
+		t.Errorf("restored history = %+v", msgs)
+	}
+
+	// 会话超时只移出内存，停机超过会话超时时间后仍可恢复
+	restarted.expire(restarted.GetSession("persist", nil))
+	record.UpdatedAt = time.Now().Add(-24 * time.Hour)
+	if err = store.Save(record); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if msgs = restarted.GetSession("persist", nil).history.getMessage(); len(msgs) != 5 {
+		t.Errorf("history after expire = %+v, want restored from store", msgs)
+	}
+
+	restarted.removeById("persist")
+	if _, ok, _ = store.Load("persist"); ok {
+		t.Error("removeById() should delete the stored session")
+	}
+}
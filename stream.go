@@ -0,0 +1,267 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 上午10:12:00
+// @Desc 流式 (server-sent events) 对话请求
+package ai_sdk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	streamDataPrefix = "data:"
+	streamDoneFlag   = "[DONE]"
+	streamObject     = "chat.completion"
+)
+
+var streamClosedErr = errors.New("stream already closed") // 流已关闭
+
+// ChatCompletionStream 流式响应读取器，通过 Recv 逐块读取，读取完毕返回 io.EOF
+type ChatCompletionStream struct {
+	resp   *http.Response
+	reader *bufio.Reader
+	acc    streamAccumulator
+	done   bool
+	closed bool
+}
+
+// SendStream 发起流式对话请求 (stream: true)，调用方需在读取完毕后 Close
+func (a AIClient) SendStream(req Request) (*ChatCompletionStream, error) {
+	return a.SendStreamWithContext(context.Background(), req)
+}
+
+// SendStreamWithContext 携带 context 发起流式对话请求，ctx 取消后 Recv 将返回错误
+func (a AIClient) SendStreamWithContext(ctx context.Context, req Request) (*ChatCompletionStream, error) {
+	request := a.convertReq(req)
+	request.Stream = true
+	request.StreamOptions = req.StreamOptions
+	resp, _, err := doRequest(ctx, a, request)
+	if err != nil {
+		return nil, fmt.Errorf("send stream request failed: %w", err)
+	}
+	return newChatCompletionStream(resp), nil
+}
+
+func newChatCompletionStream(resp *http.Response) *ChatCompletionStream {
+	return &ChatCompletionStream{
+		resp:   resp,
+		reader: bufio.NewReader(resp.Body),
+		acc:    newStreamAccumulator(),
+	}
+}
+
+// Recv 读取下一个数据块，流正常结束 ([DONE] 或连接关闭) 时返回 io.EOF
+func (s *ChatCompletionStream) Recv() (chunk ChatCompletionStreamResponse, err error) {
+	if s.closed {
+		return chunk, streamClosedErr
+	}
+	if s.done {
+		return chunk, io.EOF
+	}
+	for {
+		data, err := s.readEvent()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				s.done = true
+			}
+			return chunk, err
+		}
+		if data == "" {
+			continue
+		}
+		if data == streamDoneFlag {
+			s.done = true
+			return chunk, io.EOF
+		}
+		if err = json.Unmarshal([]byte(data), &chunk); err != nil {
+			return chunk, fmt.Errorf("stream json.Unmarshal(data, &chunk): %w", err)
+		}
+		if chunk.ID == "" && len(chunk.Choices) == 0 && chunk.Usage == nil { // 部分中转会以数据块的形式返回错误信息
+			var errResp errorResponse
+			if json.Unmarshal([]byte(data), &errResp) == nil && errResp.Error.Message != "" {
+				s.acc.err = errResp.Error
+				return chunk, fmt.Errorf("stream error: %s", errResp.Error.Message)
+			}
+		}
+		s.acc.add(chunk)
+		reportUsage(s.resp.Body, chunk.Usage)
+		return chunk, nil
+	}
+}
+
+// readEvent 读取一个完整的 SSE 事件并返回其 data 字段（多行 data 以换行拼接）
+func (s *ChatCompletionStream) readEvent() (string, error) {
+	var data []string
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("stream read err: %w", err)
+		}
+		eof := err != nil
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, streamDataPrefix) {
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, streamDataPrefix), " "))
+		}
+		// 其余字段 (event: / id: / retry: / 注释) 忽略
+		if (line == "" || eof) && len(data) != 0 { // 空行表示事件结束
+			return strings.Join(data, "\n"), nil
+		}
+		if eof {
+			return "", io.EOF
+		}
+	}
+}
+
+// Response 返回截至目前已接收数据块聚合成的完整响应
+func (s *ChatCompletionStream) Response() Response[DefalutResponse] {
+	return s.acc.response()
+}
+
+// Close 关闭流
+func (s *ChatCompletionStream) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.resp.Body.Close()
+}
+
+// streamAccumulator 将流式数据块聚合为完整响应
+type streamAccumulator struct {
+	base    ChatCompletionStreamResponse
+	choices map[int]*choiceBuilder
+	usage   *Usage
+	err     RespError
+}
+
+type choiceBuilder struct {
+	role         string
+	content      bytes.Buffer
+	finishReason string
+	logprobs     interface{}
+	toolCalls    map[int]*toolCallBuilder
+}
+
+type toolCallBuilder struct {
+	id        string
+	typ       string
+	name      string
+	arguments bytes.Buffer
+}
+
+func newStreamAccumulator() streamAccumulator {
+	return streamAccumulator{choices: make(map[int]*choiceBuilder)}
+}
+
+func (acc *streamAccumulator) add(chunk ChatCompletionStreamResponse) {
+	if acc.base.ID == "" {
+		acc.base.ID = chunk.ID
+		acc.base.Created = chunk.Created
+		acc.base.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		acc.usage = chunk.Usage
+	}
+	for _, c := range chunk.Choices {
+		cb, ok := acc.choices[c.Index]
+		if !ok {
+			cb = &choiceBuilder{toolCalls: make(map[int]*toolCallBuilder)}
+			acc.choices[c.Index] = cb
+		}
+		if c.Delta.Role != "" {
+			cb.role = c.Delta.Role
+		}
+		cb.content.WriteString(c.Delta.Content)
+		if c.FinishReason != "" {
+			cb.finishReason = c.FinishReason
+		}
+		if c.Logprobs != nil {
+			cb.logprobs = c.Logprobs
+		}
+		for _, tc := range c.Delta.ToolCalls {
+			tb, ok := cb.toolCalls[tc.Index]
+			if !ok {
+				tb = &toolCallBuilder{}
+				cb.toolCalls[tc.Index] = tb
+			}
+			if tc.ID != "" {
+				tb.id = tc.ID
+			}
+			if tc.Type != "" {
+				tb.typ = tc.Type
+			}
+			tb.name += tc.Function.Name
+			tb.arguments.WriteString(tc.Function.Arguments)
+		}
+	}
+}
+
+func (acc *streamAccumulator) response() (resp Response[DefalutResponse]) {
+	resp.ID = acc.base.ID
+	resp.Object = streamObject
+	resp.Created = acc.base.Created
+	resp.Model = acc.base.Model
+	resp.err = acc.err
+	indexes := make([]int, 0, len(acc.choices))
+	for i := range acc.choices {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	choices := make([]Choice, 0, len(indexes))
+	for _, i := range indexes {
+		cb := acc.choices[i]
+		role := cb.role
+		if role == "" {
+			role = assistantRole
+		}
+		choices = append(choices, Choice{
+			Index: i,
+			Message: Message{
+				Role:      role,
+				Content:   cb.content.String(),
+				ToolCalls: cb.buildToolCalls(),
+			},
+			Logprobs:     cb.logprobs,
+			FinishReason: cb.finishReason,
+		})
+	}
+	resp.data = DefalutResponse{Choices: choices, Usage: acc.usage}
+	return resp
+}
+
+func (cb *choiceBuilder) buildToolCalls() []ToolCall {
+	if len(cb.toolCalls) == 0 {
+		return nil
+	}
+	indexes := make([]int, 0, len(cb.toolCalls))
+	for i := range cb.toolCalls {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	calls := make([]ToolCall, 0, len(indexes))
+	for _, i := range indexes {
+		tb := cb.toolCalls[i]
+		typ := tb.typ
+		if typ == "" {
+			typ = defaultFuncType
+		}
+		calls = append(calls, ToolCall{
+			ID:   tb.id,
+			Type: typ,
+			Function: FunctionCall{
+				Name:      tb.name,
+				Arguments: tb.arguments.String(),
+			},
+		})
+	}
+	return calls
+}
@@ -0,0 +1,155 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 上午10:40:00
+// @Desc 流式请求测试
+package ai_sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newSSEServer SSE 测试服务，第 n 次请求推送 rounds[n] 中的数据块
+func newSSEServer(t *testing.T, rounds [][]string, check func(round int, req ChatCompletionRequest)) *httptest.Server {
+	t.Helper()
+	var round int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&round, 1)) - 1
+		var req ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request err: %v", err)
+		}
+		if check != nil {
+			check(n, req)
+		}
+		if n >= len(rounds) {
+			t.Errorf("unexpected request round %d", n)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, chunk := range rounds[n] {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+			flusher.Flush()
+		}
+	}))
+}
+
+func newTestClient(url string) *AIClient {
+	return NewAIClient([]config.APIConfig{{Url: url, AuthList: []string{"sk-test"}}}, config.DefaultModel, config.DefaultEndPoint, 10)
+}
+
+func TestAIClient_SendStream(t *testing.T) {
+	tests := []struct {
+		name         string
+		chunks       []string
+		includeUsage bool
+		wantDeltas   []string
+		wantContent  string
+		wantFinish   string
+		wantToolCall *ToolCall
+		wantUsage    *Usage
+	}{
+		{
+			name: "content with usage",
+			chunks: []string{
+				`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}`,
+				`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":"你好"},"finish_reason":null}]}`,
+				`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":"，世界"},"finish_reason":"stop"}]}`,
+				`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}`,
+				streamDoneFlag,
+			},
+			includeUsage: true,
+			wantDeltas:   []string{"", "你好", "，世界", ""},
+			wantContent:  "你好，世界",
+			wantFinish:   "stop",
+			wantUsage:    &Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7},
+		},
+		{
+			name: "fragmented tool call",
+			chunks: []string{
+				`{"id":"c2","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+				`{"id":"c2","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+				`{"id":"c2","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"泉州\"}"}}]},"finish_reason":"tool_calls"}]}`,
+				streamDoneFlag,
+			},
+			wantDeltas: []string{"", "", ""},
+			wantFinish: ToolsCallFinishReason,
+			wantToolCall: &ToolCall{
+				ID:       "call_1",
+				Type:     "function",
+				Function: FunctionCall{Name: "get_weather", Arguments: `{"city":"泉州"}`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSSEServer(t, [][]string{tt.chunks}, func(_ int, req ChatCompletionRequest) {
+				if !req.Stream {
+					t.Errorf("request stream = false, want true")
+				}
+				if tt.includeUsage && (req.StreamOptions == nil || !req.StreamOptions.IncludeUsage) {
+					t.Errorf("request stream_options = %v, want include_usage", req.StreamOptions)
+				}
+			})
+			defer server.Close()
+
+			req := Request{Messages: []Message{{Role: userRole, Content: "hi"}}}
+			if tt.includeUsage {
+				req.StreamOptions = &StreamOptions{IncludeUsage: true}
+			}
+			stream, err := newTestClient(server.URL).SendStream(req)
+			if err != nil {
+				t.Fatalf("SendStream() error = %v", err)
+			}
+			defer stream.Close()
+
+			var deltas []string
+			for {
+				chunk, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Recv() error = %v", err)
+				}
+				var delta string
+				for _, c := range chunk.Choices {
+					delta += c.Delta.Content
+				}
+				deltas = append(deltas, delta)
+			}
+			if strings.Join(deltas, "|") != strings.Join(tt.wantDeltas, "|") {
+				t.Errorf("deltas = %q, want %q", deltas, tt.wantDeltas)
+			}
+
+			resp := stream.Response()
+			choice := resp.GetData().Choices[0]
+			if choice.Message.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", choice.Message.Content, tt.wantContent)
+			}
+			if choice.FinishReason != tt.wantFinish {
+				t.Errorf("finish_reason = %q, want %q", choice.FinishReason, tt.wantFinish)
+			}
+			if tt.wantToolCall != nil {
+				if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0] != *tt.wantToolCall {
+					t.Errorf("tool_calls = %+v, want %+v", choice.Message.ToolCalls, *tt.wantToolCall)
+				}
+			}
+			if tt.wantUsage != nil {
+				if got := resp.GetData().Usage; got == nil || *got != *tt.wantUsage {
+					t.Errorf("usage = %+v, want %+v", got, tt.wantUsage)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午0:10:00
+// @Desc 滚动摘要记忆：将被移出上下文的问答交给模型总结，摘要作为紧随预设的消息保留在上下文中
+package ai_sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/config"
+	"github.com/rs/zerolog/log"
+	"strings"
+)
+
+const (
+	defaultSummaryTokens = 512 // 摘要默认最大 token 数
+	summaryPrefix        = "以下是此前对话的摘要：\n"
+	summaryPrompt        = "你是对话记录的摘要助手。请将已有摘要与新增的对话记录合并为一份新的摘要，保留人物、事实、约定、偏好与未完成的事项，省略寒暄与重复内容，使用与对话相同的语言，只输出摘要本身。"
+)
+
+var emptySummaryErr = errors.New("empty summary") // 模型返回了空摘要
+
+// Summarizer 将已有摘要 summary 与被移出上下文的消息 evicted 合并为新的摘要
+// client 与 model 为会话使用的客户端与模型 (见 WithSessionClient / WithSessionModel)
+type Summarizer func(ctx context.Context, client *AIClient, model string, summary string, evicted []Message) (string, error)
+
+// summarizeFunc 绑定了会话客户端与模型的 Summarizer
+type summarizeFunc func(ctx context.Context, summary string, evicted []Message) (string, error)
+
+// SetSummarizer 设置会话的滚动摘要记忆，设置后新建的会话在移除最早的问答时会先将其合并进摘要，nil 表示不使用摘要 (直接丢弃)
+// 一般使用 ModelSummarizer，摘要失败时被移出的问答会被丢弃并记录日志，不影响本轮对话
+func (s *Session) SetSummarizer(summarizer Summarizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summarizer = summarizer
+}
+
+// ModelSummarizer 使用会话的客户端与模型生成摘要，maxTokens 为摘要的最大 token 数，传入 0 使用默认值 512
+func ModelSummarizer(maxTokens int) Summarizer {
+	if maxTokens <= 0 {
+		maxTokens = defaultSummaryTokens
+	}
+	return func(ctx context.Context, client *AIClient, model string, summary string, evicted []Message) (string, error) {
+		var sb strings.Builder
+		if summary != "" {
+			sb.WriteString("已有摘要：\n" + summary + "\n\n")
+		}
+		sb.WriteString("新增对话记录：\n" + formatTranscript(evicted))
+		resp, err := client.SendWithContext(ctx, Request{
+			Model: model,
+			Messages: []Message{
+				{Role: systemRole, Content: summaryPrompt},
+				{Role: userRole, Content: sb.String()},
+			},
+			ChatParams: config.ChatParams{MaxTokens: Ptr(maxTokens)},
+		})
+		if err != nil {
+			return "", fmt.Errorf("ModelSummarizer aiclient.Send err: %w", err)
+		}
+		choices := resp.GetData().Choices
+		if len(choices) == 0 {
+			return "", emptyChoicesErr
+		}
+		content := strings.TrimSpace(choices[0].Message.Content)
+		if content == "" {
+			return "", emptySummaryErr
+		}
+		return content, nil
+	}
+}
+
+// formatTranscript 将消息格式化为便于总结的文本记录
+func formatTranscript(msgs []Message) string {
+	var sb strings.Builder
+	for _, msg := range msgs {
+		switch msg.Role {
+		case userRole:
+			sb.WriteString("用户: " + msg.Content + "\n")
+		case assistantRole:
+			for _, call := range msg.ToolCalls {
+				sb.WriteString("助手调用工具: " + call.Function.Name + "(" + call.Function.Arguments + ")\n")
+			}
+			if msg.Content != "" {
+				sb.WriteString("助手: " + msg.Content + "\n")
+			}
+		case toolRole:
+			sb.WriteString("工具结果: " + msg.Content + "\n")
+		default:
+			sb.WriteString(msg.Role + ": " + msg.Content + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// summaryMessage 摘要消息 concurrent unsafe (需持有 h.mu)，没有摘要时 ok 为 false
+func (h *history) summaryMessage() (msg Message, ok bool) {
+	if h.summary == "" {
+		return msg, false
+	}
+	return Message{Role: systemRole, Content: summaryPrefix + h.summary}, true
+}
+
+// foldSummary 将被移出的问答 (包括此前因问答轮数上限移除的问答) 合并进摘要
+func (h *history) foldSummary(ctx context.Context, evicted []dialogEntry) {
+	h.mu.Lock()
+	entries := append(h.pending, evicted...)
+	h.pending = nil
+	summary := h.summary
+	h.mu.Unlock()
+	if len(entries) == 0 {
+		return
+	}
+	var msgs []Message
+	for _, entry := range entries {
+		msgs = append(msgs, entry.question)
+		msgs = append(msgs, entry.answerList...)
+	}
+	newSummary, err := h.summarize(ctx, summary, msgs)
+	if err != nil {
+		log.Error().Err(err).Str("sessionId", h.sessionId).Int("evicted", len(entries)).Msg("summarize evicted dialog failed")
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.summary = newSummary
+	h.save()
+}
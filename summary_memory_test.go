@@ -0,0 +1,126 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午0:30:00
+// @Desc 滚动摘要记忆测试
+package ai_sdk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_history_summary(t *testing.T) {
+	countTokens := func(msg Message) int { // 每条消息按 10 个 token 计算
+		return 10
+	}
+	turn := func(n string) dialogEntry {
+		return dialogEntry{question: Message{Role: userRole, Content: "问题" + n}, answerList: answerList{{Role: assistantRole, Content: "回答" + n}}}
+	}
+	answer := func(msgs answerList, tools *[]Tool) (answerList, error) {
+		return answerList{{Role: assistantRole, Content: "新回答"}}, nil
+	}
+	tests := []struct {
+		name        string
+		maxHistory  int
+		budget      int
+		summaryErr  error
+		wantEvicted []string // 交给摘要函数的问题
+		wantSummary string
+		wantPending int
+	}{
+		{name: "evicted by budget", budget: 60, wantEvicted: []string{"问题1"}, wantSummary: "摘要", wantPending: 1},
+		{name: "evicted by max history", maxHistory: 2, budget: 1000, wantEvicted: []string{"问题1"}, wantSummary: "摘要", wantPending: 1},
+		{name: "fits", budget: 1000},
+		{name: "summarize failed", budget: 60, summaryErr: errors.New("boom"), wantEvicted: []string{"问题1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotEvicted []string
+			h := &history{
+				maxHistory:  tt.maxHistory,
+				system:      Message{Role: systemRole, Content: "预设"},
+				countTokens: countTokens,
+				countTools:  estimateToolsTokens,
+				summarize: func(ctx context.Context, summary string, evicted []Message) (string, error) {
+					for _, msg := range evicted {
+						if msg.Role == userRole {
+							gotEvicted = append(gotEvicted, msg.Content)
+						}
+					}
+					return "摘要", tt.summaryErr
+				},
+			}
+			for _, n := range []string{"1", "2", "3"} {
+				h.addLast(turn(n))
+			}
+			var sent answerList
+			_, err := h.handleQuestion(context.Background(), "新问题", tt.budget, func(msgs answerList, tools *[]Tool) (answerList, error) {
+				sent = msgs
+				return answer(msgs, tools)
+			})
+			if err != nil {
+				t.Fatalf("handleQuestion() error = %v", err)
+			}
+			if strings.Join(gotEvicted, ",") != strings.Join(tt.wantEvicted, ",") {
+				t.Errorf("summarized questions = %v, want %v", gotEvicted, tt.wantEvicted)
+			}
+			if h.summary != tt.wantSummary || len(h.pending) != tt.wantPending {
+				t.Errorf("summary = %q, pending = %d, want %q, %d", h.summary, len(h.pending), tt.wantSummary, tt.wantPending)
+			}
+			if tt.wantSummary != "" && (sent[1].Role != systemRole || sent[1].Content != summaryPrefix+tt.wantSummary) {
+				t.Errorf("summary message not pinned after system: %+v", sent)
+			}
+			if used := len(sent) * 10; used > tt.budget {
+				t.Errorf("request uses %d tokens, budget %d", used, tt.budget)
+			}
+		})
+	}
+}
+
+func TestModelSummarizer(t *testing.T) {
+	server := newJSONServer(t, []string{" 用户喜欢晴天 "}, func(round int, req ChatCompletionRequest) {
+		if len(req.Messages) != 2 || req.Messages[0].Content != summaryPrompt {
+			t.Errorf("unexpected messages: %+v", req.Messages)
+		}
+		content := req.Messages[1].Content
+		for _, want := range []string{"已有摘要：\n用户叫小明", "用户: 天气如何", "助手调用工具: weather({})", "工具结果: 晴", "助手: 今天晴"} {
+			if !strings.Contains(content, want) {
+				t.Errorf("transcript missing %q: %s", want, content)
+			}
+		}
+		if req.MaxTokens == nil || *req.MaxTokens != 100 || req.Model != "summary-model" {
+			t.Errorf("max_tokens = %v, model = %q, want 100 and the session model", req.MaxTokens, req.Model)
+		}
+	})
+	defer server.Close()
+	summary, err := ModelSummarizer(100)(context.Background(), newTestClient(server.URL), "summary-model", "用户叫小明", []Message{
+		{Role: userRole, Content: "天气如何"},
+		{Role: assistantRole, ToolCalls: []ToolCall{{ID: "call_1", Function: FunctionCall{Name: "weather", Arguments: "{}"}}}},
+		{Role: toolRole, ToolCallID: "call_1", Content: "晴"},
+		{Role: assistantRole, Content: "今天晴"},
+	})
+	if err != nil || summary != "用户喜欢晴天" {
+		t.Errorf("ModelSummarizer() = %q, %v", summary, err)
+	}
+}
+
+func TestSession_SetSummarizer_sessionClient(t *testing.T) {
+	client := newTestClient("http://127.0.0.1:1")
+	var gotClient *AIClient
+	var gotModel string
+	s := NewSession("摘要客户端测试", 2)
+	s.SetSummarizer(func(ctx context.Context, client *AIClient, model string, summary string, evicted []Message) (string, error) {
+		gotClient, gotModel = client, model
+		return "摘要", nil
+	})
+	info := s.GetSession("summary_client", nil, WithSessionClient(client), WithSessionModel("session-model"))
+	if _, err := info.history.summarize(context.Background(), "", nil); err != nil {
+		t.Fatalf("summarize() error = %v", err)
+	}
+	if gotClient != client || gotModel != "session-model" {
+		t.Errorf("Summarizer got client %p model %q, want the session client %p and model", gotClient, gotModel, client)
+	}
+	s.Close()
+}
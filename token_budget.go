@@ -0,0 +1,100 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午10:00:00
+// @Desc 上下文 token 预算：按模型上下文窗口估算请求可携带的历史消息
+package ai_sdk
+
+import (
+	"encoding/json"
+	"github.com/Clov614/go-ai-sdk/config"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultContextWindow = 8192 // 未知模型的上下文窗口
+	defaultReplyReserve  = 1024 // 未设置 max_tokens 时为回答预留的 token 数
+	messageTokenOverhead = 3    // 每条消息的格式开销 (<|start|>role ... <|end|>)，精确计算与估算共用
+	replyTokenOverhead   = 3    // 每次回答的起始开销 (<|start|>assistant<|message|>)
+	toolCallTokens       = 3    // 每个工具调用的格式开销
+)
+
+// modelContextWindows 模型名前缀 -> 上下文窗口，按前缀从长到短匹配
+var modelContextWindows = []struct {
+	prefix string
+	window int
+}{
+	{"gpt-3.5-turbo-instruct", 4096},
+	{"gpt-3.5-turbo", 16385},
+	{"gpt-4-32k", 32768},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-1106", 128000},
+	{"gpt-4-0125", 128000},
+	{"gpt-4.1", 1047576},
+	{"gpt-4o", 128000},
+	{"gpt-4", 8192},
+	{"gpt-5", 400000},
+	{"o1-mini", 128000},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4-mini", 200000},
+}
+
+// contextWindow 获取模型的上下文窗口，configured 为配置的 context_window，大于 0 时优先
+func contextWindow(model string, configured int) int {
+	if configured > 0 {
+		return configured
+	}
+	for _, m := range modelContextWindows {
+		if strings.HasPrefix(model, m.prefix) {
+			return m.window
+		}
+	}
+	return defaultContextWindow
+}
+
+// tokenBudget 请求上下文 (消息与工具定义) 可用的 token 数：模型上下文窗口减去为回答预留的 token 数
+// configured 为配置的 context_window，0 表示按模型名推断
+func tokenBudget(model string, configured int, params config.ChatParams) int {
+	reserve := defaultReplyReserve
+	if params.MaxCompletionTokens != nil {
+		reserve = *params.MaxCompletionTokens
+	} else if params.MaxTokens != nil {
+		reserve = *params.MaxTokens
+	}
+	return contextWindow(model, configured) - reserve - replyTokenOverhead
+}
+
+// estimateTokens 粗略估算文本的 token 数：非 ASCII 字符 (如中文) 按每字 1 个 token，ASCII 按每 4 个字符 1 个 token
+func estimateTokens(text string) int {
+	ascii, other := 0, 0
+	for i := 0; i < len(text); {
+		if text[i] < utf8.RuneSelf {
+			ascii++
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		other++
+		i += size
+	}
+	return other + (ascii+3)/4
+}
+
+// estimateMessageTokens 估算单条消息的 token 数，包含消息格式开销与工具调用，与 countMessageTokens 统计相同的字段
+func estimateMessageTokens(msg Message) int {
+	n := messageTokenOverhead + estimateTokens(msg.Role) + estimateTokens(msg.Content) + estimateTokens(msg.ToolCallID)
+	for _, call := range msg.ToolCalls {
+		n += toolCallTokens + estimateTokens(call.ID) + estimateTokens(call.Function.Name) + estimateTokens(call.Function.Arguments)
+	}
+	return n
+}
+
+// estimateToolsTokens 估算工具定义占用的 token 数
+func estimateToolsTokens(tools *[]Tool) int {
+	if tools == nil || len(*tools) == 0 {
+		return 0
+	}
+	data, _ := json.Marshal(tools)
+	return estimateTokens(string(data))
+}
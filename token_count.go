@@ -0,0 +1,170 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午11:40:00
+// @Desc 使用 BPE 分词器精确计算消息与工具定义的 token 数，词表不可用时回退到估算
+package ai_sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/tokenizer"
+	"github.com/rs/zerolog/log"
+	"sort"
+	"strings"
+)
+
+// 消息、回答与工具调用的格式开销见 token_budget.go，与估算共用
+const (
+	toolsDefinitionTokens = 9 // 工具定义的固定开销
+	toolsSystemDiscount   = 4 // 同时存在预设与工具定义时少计的 token 数
+)
+
+// CountTokens 计算一次请求中消息与工具定义占用的 token 数 (含回答起始开销)，与接口返回的 prompt_tokens 基本一致
+// 模型对应的词表未加载时返回 tokenizer.VocabNotFoundErr 等错误
+func CountTokens(model string, msgs []Message, tools *[]Tool) (int, error) {
+	enc, err := tokenizer.ForModel(model)
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens: %w", err)
+	}
+	n := replyTokenOverhead
+	hasSystem := false
+	for _, msg := range msgs {
+		n += countMessageTokens(enc, msg)
+		hasSystem = hasSystem || msg.Role == systemRole
+	}
+	if tools != nil && len(*tools) != 0 {
+		n += countToolsTokens(enc, tools)
+		if hasSystem {
+			n -= toolsSystemDiscount
+		}
+	}
+	return n, nil
+}
+
+// countMessageTokens 计算单条消息的 token 数，与 estimateMessageTokens 统计相同的字段
+func countMessageTokens(enc *tokenizer.Encoding, msg Message) int {
+	n := messageTokenOverhead + enc.Count(msg.Role) + enc.Count(msg.Content) + enc.Count(msg.ToolCallID)
+	for _, call := range msg.ToolCalls {
+		n += toolCallTokens + enc.Count(call.ID) + enc.Count(call.Function.Name) + enc.Count(call.Function.Arguments)
+	}
+	return n
+}
+
+// countToolsTokens 计算工具定义的 token 数，工具定义在模型侧以 TypeScript 命名空间的形式呈现
+func countToolsTokens(enc *tokenizer.Encoding, tools *[]Tool) int {
+	return enc.Count(formatToolDefinitions(*tools)) + toolsDefinitionTokens
+}
+
+// formatToolDefinitions 将工具定义格式化为模型侧的 TypeScript 命名空间表示
+func formatToolDefinitions(tools []Tool) string {
+	var sb strings.Builder
+	sb.WriteString("namespace functions {\n\n")
+	for _, tool := range tools {
+		fn := tool.Function
+		if fn.Description != "" {
+			sb.WriteString("// " + fn.Description + "\n")
+		}
+		if len(fn.Parameters.Properties) == 0 {
+			sb.WriteString("type " + fn.Name + " = () => any;\n\n")
+			continue
+		}
+		sb.WriteString("type " + fn.Name + " = (_: {\n")
+		formatObjectProperties(&sb, fn.Parameters.Properties, fn.Parameters.Required)
+		sb.WriteString("}) => any;\n\n")
+	}
+	sb.WriteString("} // namespace functions")
+	return sb.String()
+}
+
+// formatObjectProperties 按属性名顺序写出对象属性，非必填属性带 ?
+func formatObjectProperties(sb *strings.Builder, props Properties, required []string) {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop := props[name]
+		if prop.Description != "" {
+			sb.WriteString("// " + prop.Description + "\n")
+		}
+		optional := "?"
+		for _, r := range required {
+			if r == name {
+				optional = ""
+				break
+			}
+		}
+		sb.WriteString(name + optional + ": " + formatPropertyType(prop) + ",\n")
+	}
+}
+
+// formatPropertyType 将属性类型格式化为 TypeScript 类型
+func formatPropertyType(prop Property) string {
+	if len(prop.Enum) != 0 {
+		values := make([]string, len(prop.Enum))
+		for i, e := range prop.Enum {
+			v, err := enumValue(prop.Type, e)
+			if err != nil {
+				v = e
+			}
+			b, _ := json.Marshal(v)
+			values[i] = string(b)
+		}
+		return strings.Join(values, " | ")
+	}
+	if variants := append(append([]Property(nil), prop.AnyOf...), prop.OneOf...); len(variants) != 0 {
+		types := make([]string, len(variants))
+		for i, v := range variants {
+			types[i] = formatPropertyType(v)
+		}
+		return strings.Join(types, " | ")
+	}
+	switch prop.Type {
+	case "object":
+		if len(prop.Properties) == 0 {
+			return "object"
+		}
+		var sb strings.Builder
+		sb.WriteString("{\n")
+		formatObjectProperties(&sb, prop.Properties, prop.Required)
+		sb.WriteString("}")
+		return sb.String()
+	case "array":
+		if prop.Items == nil {
+			return "any[]"
+		}
+		return formatPropertyType(*prop.Items) + "[]"
+	case "integer":
+		return "number"
+	case "":
+		return "any"
+	}
+	return prop.Type
+}
+
+// messageTokenCounter 获取模型的单条消息计数函数，词表不可用时回退到 estimateMessageTokens
+func messageTokenCounter(model string) func(msg Message) int {
+	enc, err := tokenizer.ForModel(model)
+	if err != nil {
+		log.Debug().Err(err).Str("model", model).Msg("tokenizer unavailable, estimating message tokens")
+		return estimateMessageTokens
+	}
+	return func(msg Message) int {
+		return countMessageTokens(enc, msg)
+	}
+}
+
+// toolsTokenCounter 获取模型的工具定义计数函数，词表不可用时回退到 estimateToolsTokens
+func toolsTokenCounter(model string) func(tools *[]Tool) int {
+	enc, err := tokenizer.ForModel(model)
+	if err != nil {
+		return estimateToolsTokens
+	}
+	return func(tools *[]Tool) int {
+		if tools == nil || len(*tools) == 0 {
+			return 0
+		}
+		return countToolsTokens(enc, tools)
+	}
+}
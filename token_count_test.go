@@ -0,0 +1,103 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午11:50:00
+// @Desc token 计数测试
+package ai_sdk
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/global"
+	"github.com/Clov614/go-ai-sdk/tokenizer"
+	"strings"
+	"testing"
+)
+
+func Test_formatToolDefinitions(t *testing.T) {
+	tools := []Tool{
+		{Type: "function", Function: Function{
+			Name:        "get_weather",
+			Description: "查询天气",
+			Parameters: FunctionParameter{
+				Type: global.ObjType,
+				Properties: Properties{
+					"city": {Type: global.StringType, Description: "城市"},
+					"unit": {Type: global.StringType, Enum: []string{"c", "f"}},
+					"days": {Type: global.ArrayType, Items: &Property{Type: global.IntType}},
+					"geo": {Type: global.ObjType, Properties: Properties{
+						"lat": {Type: global.NumberType},
+					}, Required: []string{"lat"}},
+				},
+				Required: []string{"city"},
+			},
+		}},
+		{Type: "function", Function: Function{Name: "now"}},
+	}
+	want := "namespace functions {\n\n" +
+		"// 查询天气\n" +
+		"type get_weather = (_: {\n" +
+		"// 城市\n" +
+		"city: string,\n" +
+		"days?: number[],\n" +
+		"geo?: {\nlat: number,\n},\n" +
+		"unit?: \"c\" | \"f\",\n" +
+		"}) => any;\n\n" +
+		"type now = () => any;\n\n" +
+		"} // namespace functions"
+	if got := formatToolDefinitions(tools); got != want {
+		t.Errorf("formatToolDefinitions() = %q, want %q", got, want)
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	// 仅含单字节的词表：每个字节计为一个 token
+	var sb strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	if _, err := tokenizer.Load(tokenizer.Cl100kBase, strings.NewReader(sb.String())); err != nil {
+		t.Fatalf("tokenizer.Load() error = %v", err)
+	}
+	tools := &[]Tool{{Type: "function", Function: Function{Name: "f"}}}
+	toolsTokens := len(formatToolDefinitions(*tools)) + toolsDefinitionTokens
+	tests := []struct {
+		name  string
+		msgs  []Message
+		tools *[]Tool
+		want  int
+	}{
+		{
+			name: "messages",
+			msgs: []Message{{Role: systemRole, Content: "ab"}, {Role: userRole, Content: "你"}},
+			want: replyTokenOverhead + (3 + 6 + 2) + (3 + 4 + 3),
+		},
+		{
+			name: "tool calls",
+			msgs: []Message{{Role: assistantRole, ToolCalls: []ToolCall{{ID: "call_1", Function: FunctionCall{Name: "f", Arguments: "{}"}}}}},
+			want: replyTokenOverhead + (3 + 9) + (toolCallTokens + 6 + 1 + 2),
+		},
+		{
+			name: "tool message",
+			msgs: []Message{{Role: toolRole, ToolCallID: "call_1", Content: "ok"}},
+			want: replyTokenOverhead + (3 + 4 + 2 + 6),
+		},
+		{
+			name:  "tools with system",
+			msgs:  []Message{{Role: systemRole, Content: "ab"}},
+			tools: tools,
+			want:  replyTokenOverhead + (3 + 6 + 2) + toolsTokens - toolsSystemDiscount,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CountTokens("gpt-4", tt.msgs, tt.tools)
+			if err != nil || got != tt.want {
+				t.Errorf("CountTokens() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := CountTokens("unknown-model", nil, nil); !errors.Is(err, tokenizer.UnknownEncodingErr) {
+		t.Errorf("CountTokens() unknown model error = %v", err)
+	}
+}
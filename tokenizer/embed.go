@@ -0,0 +1,23 @@
+//go:build tiktoken_embed
+
+// Package tokenizer
+// @Author Clover
+// @Data 2026/10/18 下午11:10:00
+// @Desc 使用 -tags tiktoken_embed 构建时将 vocab 目录下的词表嵌入二进制
+package tokenizer
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed vocab/*.tiktoken
+var vocabFS embed.FS
+
+func init() {
+	sub, err := fs.Sub(vocabFS, "vocab")
+	if err != nil {
+		panic(err)
+	}
+	embedded = sub
+}
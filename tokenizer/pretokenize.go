@@ -0,0 +1,223 @@
+// Package tokenizer
+// @Author Clover
+// @Data 2026/10/18 下午10:50:00
+// @Desc 预分词：手写实现 tiktoken 的切分正则 (Go regexp 不支持其中的 (?!\S) 断言)
+package tokenizer
+
+import "unicode"
+
+// encodingSpec 编码的预分词规则与特殊 token
+type encodingSpec struct {
+	split    func(text string) []string
+	specials map[string]int
+}
+
+var encodingSpecs = map[string]encodingSpec{
+	// (?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+	Cl100kBase: {
+		split: func(text string) []string { return splitText(text, matchCl100k) },
+		specials: map[string]int{
+			"<|endoftext|>":   100257,
+			"<|fim_prefix|>":  100258,
+			"<|fim_middle|>":  100259,
+			"<|fim_suffix|>":  100260,
+			"<|endofprompt|>": 100276,
+		},
+	},
+	// [^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+	// |[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+	// |\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+	O200kBase: {
+		split: func(text string) []string { return splitText(text, matchO200k) },
+		specials: map[string]int{
+			"<|endoftext|>":   199999,
+			"<|endofprompt|>": 200018,
+		},
+	},
+}
+
+// splitText 从左到右依次匹配，match 返回从 i 开始匹配的长度 (rune 数)
+func splitText(text string, match func(rs []rune, i int) int) []string {
+	rs := []rune(text)
+	pieces := make([]string, 0, len(rs)/4+1)
+	for i := 0; i < len(rs); {
+		l := match(rs, i)
+		if l <= 0 {
+			l = 1
+		}
+		pieces = append(pieces, string(rs[i:i+l]))
+		i += l
+	}
+	return pieces
+}
+
+func matchCl100k(rs []rune, i int) int {
+	if l := matchContraction(rs, i); l > 0 {
+		return l
+	}
+	n, r := len(rs), rs[i]
+	// [^\r\n\p{L}\p{N}]?\p{L}+
+	start := i
+	if !unicode.IsLetter(r) && isPrefix(r) && i+1 < n && unicode.IsLetter(rs[i+1]) {
+		start = i + 1
+	}
+	if unicode.IsLetter(rs[start]) {
+		j := start + 1
+		for j < n && unicode.IsLetter(rs[j]) {
+			j++
+		}
+		return j - i
+	}
+	if l := matchDigits(rs, i); l > 0 {
+		return l
+	}
+	if l := matchSymbols(rs, i, isNewline); l > 0 {
+		return l
+	}
+	return matchWhitespace(rs, i)
+}
+
+func matchO200k(rs []rune, i int) int {
+	// 两种单词规则均先尝试带前缀，再尝试不带前缀
+	for _, matchWord := range []func(rs []rune, p int) int{matchLowerWord, matchUpperWord} {
+		starts := []int{i}
+		if isPrefix(rs[i]) && i+1 < len(rs) {
+			starts = []int{i + 1, i}
+		}
+		for _, start := range starts {
+			if end := matchWord(rs, start); end > start {
+				return end + matchContraction(rs, end) - i
+			}
+		}
+	}
+	if l := matchDigits(rs, i); l > 0 {
+		return l
+	}
+	if l := matchSymbols(rs, i, func(r rune) bool { return isNewline(r) || r == '/' }); l > 0 {
+		return l
+	}
+	return matchWhitespace(rs, i)
+}
+
+// matchLowerWord [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+，返回结束位置，不匹配时返回 p
+func matchLowerWord(rs []rune, p int) int {
+	n, q := len(rs), p
+	for q < n && isUpperClass(rs[q]) {
+		q++
+	}
+	for k := q; k >= p; k-- { // 贪婪匹配后回溯，保证后半部分至少匹配一个字符
+		if k < n && isLowerClass(rs[k]) {
+			e := k + 1
+			for e < n && isLowerClass(rs[e]) {
+				e++
+			}
+			return e
+		}
+	}
+	return p
+}
+
+// matchUpperWord [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*，返回结束位置，不匹配时返回 p
+func matchUpperWord(rs []rune, p int) int {
+	n, q := len(rs), p
+	for q < n && isUpperClass(rs[q]) {
+		q++
+	}
+	if q == p {
+		return p
+	}
+	for q < n && isLowerClass(rs[q]) {
+		q++
+	}
+	return q
+}
+
+// matchContraction (?i:'s|'t|'re|'ve|'m|'ll|'d)
+func matchContraction(rs []rune, i int) int {
+	if i+1 >= len(rs) || rs[i] != '\'' {
+		return 0
+	}
+	switch unicode.ToLower(rs[i+1]) {
+	case 's', 't', 'm', 'd':
+		return 2
+	case 'r', 'v', 'l':
+		if i+2 < len(rs) {
+			suffix := string([]rune{unicode.ToLower(rs[i+1]), unicode.ToLower(rs[i+2])})
+			if suffix == "re" || suffix == "ve" || suffix == "ll" {
+				return 3
+			}
+		}
+	}
+	return 0
+}
+
+// matchDigits \p{N}{1,3}
+func matchDigits(rs []rune, i int) int {
+	j := i
+	for j < len(rs) && j-i < 3 && unicode.IsNumber(rs[j]) {
+		j++
+	}
+	return j - i
+}
+
+// matchSymbols ' ?[^\s\p{L}\p{N}]+' 后接任意个 tail 字符
+func matchSymbols(rs []rune, i int, tail func(r rune) bool) int {
+	n, j := len(rs), i
+	if rs[j] == ' ' && j+1 < n && isSymbol(rs[j+1]) {
+		j++
+	}
+	if !isSymbol(rs[j]) {
+		return 0
+	}
+	for j < n && isSymbol(rs[j]) {
+		j++
+	}
+	for j < n && tail(rs[j]) {
+		j++
+	}
+	return j - i
+}
+
+// matchWhitespace \s*[\r\n]+|\s+(?!\S)|\s+
+func matchWhitespace(rs []rune, i int) int {
+	n, j := len(rs), i
+	for j < n && unicode.IsSpace(rs[j]) {
+		j++
+	}
+	if j == i {
+		return 0
+	}
+	for k := j - 1; k >= i; k-- { // \s*[\r\n]+ 匹配到最后一个换行符
+		if isNewline(rs[k]) {
+			return k + 1 - i
+		}
+	}
+	if j == n || j-1 == i { // \s+(?!\S) 在文本末尾匹配全部空白，否则留下最后一个空白给后续的单词
+		return j - i
+	}
+	return j - 1 - i
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
+
+// isPrefix [^\r\n\p{L}\p{N}]
+func isPrefix(r rune) bool {
+	return !isNewline(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// isSymbol [^\s\p{L}\p{N}]
+func isSymbol(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// isUpperClass [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]
+func isUpperClass(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// isLowerClass [\p{Ll}\p{Lm}\p{Lo}\p{M}]
+func isLowerClass(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
@@ -0,0 +1,150 @@
+// Package tokenizer
+// @Author Clover
+// @Data 2026/10/18 下午11:00:00
+// @Desc 词表加载与缓存：优先使用嵌入的词表 (tiktoken_embed 构建标签)，其次从词表目录加载
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultVocabDir = "./cfg/tiktoken" // 约定的词表目录 (ai_sdk.Init 使用)，文件名为 <编码名>.tiktoken
+	vocabFileExt    = ".tiktoken"
+)
+
+var (
+	mu         sync.Mutex
+	vocabDir   string                   // 词表目录，为空时不从磁盘加载
+	embedded   fs.FS                    // 嵌入的词表，由 tiktoken_embed 构建标签设置
+	encodings  = map[string]*Encoding{} // 已加载的编码
+	loadErrors = map[string]error{}     // 加载失败的编码，避免重复读取磁盘
+)
+
+// modelPrefixes 模型名前缀 -> 编码，按顺序匹配
+var modelPrefixes = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-4o", O200kBase},
+	{"gpt-4.1", O200kBase},
+	{"gpt-4.5", O200kBase},
+	{"gpt-5", O200kBase},
+	{"chatgpt-4o", O200kBase},
+	{"o1", O200kBase},
+	{"o3", O200kBase},
+	{"o4", O200kBase},
+	{"gpt-4", Cl100kBase},
+	{"gpt-3.5", Cl100kBase},
+	{"gpt-35", Cl100kBase},
+	{"text-embedding-3", Cl100kBase},
+	{"text-embedding-ada-002", Cl100kBase},
+}
+
+// SetVocabDir 设置词表目录，清除此前加载失败的记录，为空表示不从磁盘加载
+func SetVocabDir(dir string) {
+	mu.Lock()
+	defer mu.Unlock()
+	vocabDir = dir
+	loadErrors = map[string]error{}
+}
+
+// Load 从 tiktoken 格式的词表加载编码并缓存，覆盖已加载的同名编码
+func Load(name string, r io.Reader) (*Encoding, error) {
+	if _, ok := encodingSpecs[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", UnknownEncodingErr, name)
+	}
+	ranks, err := parseVocab(r)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", name, err)
+	}
+	enc, err := newEncoding(name, ranks)
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	encodings[name] = enc
+	delete(loadErrors, name)
+	return enc, nil
+}
+
+// LoadFile 从文件加载编码并缓存
+func LoadFile(name string, path string) (*Encoding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w: %w", name, VocabNotFoundErr, err)
+	}
+	defer f.Close()
+	return Load(name, f)
+}
+
+// GetEncoding 获取编码：已加载的编码 > 嵌入的词表 > 词表目录下的 <name>.tiktoken
+func GetEncoding(name string) (*Encoding, error) {
+	mu.Lock()
+	if enc, ok := encodings[name]; ok {
+		mu.Unlock()
+		return enc, nil
+	}
+	if err, ok := loadErrors[name]; ok {
+		mu.Unlock()
+		return nil, err
+	}
+	dir, efs := vocabDir, embedded
+	mu.Unlock()
+
+	enc, err := loadEncoding(name, dir, efs)
+	if err != nil {
+		mu.Lock()
+		loadErrors[name] = err
+		mu.Unlock()
+		return nil, err
+	}
+	return enc, nil
+}
+
+func loadEncoding(name string, dir string, efs fs.FS) (*Encoding, error) {
+	if _, ok := encodingSpecs[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", UnknownEncodingErr, name)
+	}
+	if efs != nil {
+		f, err := efs.Open(name + vocabFileExt)
+		if err == nil {
+			defer f.Close()
+			return Load(name, f)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("load embedded %s: %w", name, err)
+		}
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("load %s: %w: vocab dir not set", name, VocabNotFoundErr)
+	}
+	return LoadFile(name, filepath.Join(dir, name+vocabFileExt))
+}
+
+// EncodingNameForModel 根据模型名获取编码名称
+func EncodingNameForModel(model string) (name string, ok bool) {
+	for _, m := range modelPrefixes {
+		if strings.HasPrefix(model, m.prefix) {
+			return m.encoding, true
+		}
+	}
+	return "", false
+}
+
+// ForModel 获取模型对应的编码
+func ForModel(model string) (*Encoding, error) {
+	name, ok := EncodingNameForModel(model)
+	if !ok {
+		return nil, fmt.Errorf("%w for model %q", UnknownEncodingErr, model)
+	}
+	return GetEncoding(name)
+}
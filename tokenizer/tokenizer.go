@@ -0,0 +1,165 @@
+// Package tokenizer
+// @Author Clover
+// @Data 2026/10/18 下午10:40:00
+// @Desc 纯 Go 实现的 BPE 分词器，兼容 tiktoken 的 cl100k_base / o200k_base 编码
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	Cl100kBase = "cl100k_base" // gpt-4 / gpt-3.5-turbo / text-embedding-3
+	O200kBase  = "o200k_base"  // gpt-4o / gpt-4.1 / o 系列
+)
+
+var (
+	UnknownEncodingErr = errors.New("unknown encoding")   // 不支持的编码
+	VocabNotFoundErr   = errors.New("vocab not found")    // 找不到词表文件
+	InvalidVocabErr    = errors.New("invalid vocab file") // 词表文件格式错误
+)
+
+// Encoding BPE 编码：按编码的预分词规则切分文本，再对每段的 UTF-8 字节做字节对合并
+type Encoding struct {
+	name     string
+	split    func(text string) []string // 预分词
+	ranks    map[string]int             // 字节序列 -> token id (即合并优先级)
+	decoder  map[int]string             // token id -> 字节序列
+	specials map[string]int             // 特殊 token
+}
+
+// Name 编码名称
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Encode 将文本编码为 token id，特殊 token (如 <|endoftext|>) 按普通文本处理
+func (e *Encoding) Encode(text string) []int {
+	tokens := make([]int, 0, len(text)/3)
+	for _, piece := range e.split(text) {
+		e.bpe(piece, func(rank int) {
+			tokens = append(tokens, rank)
+		})
+	}
+	return tokens
+}
+
+// Count 计算文本的 token 数
+func (e *Encoding) Count(text string) int {
+	n := 0
+	for _, piece := range e.split(text) {
+		e.bpe(piece, func(int) {
+			n++
+		})
+	}
+	return n
+}
+
+// Decode 将 token id 解码为文本，未知的 token 会被忽略
+func (e *Encoding) Decode(tokens []int) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString(e.decoder[token])
+	}
+	return sb.String()
+}
+
+// SpecialToken 获取特殊 token 的 id
+func (e *Encoding) SpecialToken(name string) (token int, ok bool) {
+	token, ok = e.specials[name]
+	return token, ok
+}
+
+// bpe 对一段预分词结果做字节对合并，按顺序输出 token id
+func (e *Encoding) bpe(piece string, emit func(rank int)) {
+	if rank, ok := e.ranks[piece]; ok {
+		emit(rank)
+		return
+	}
+	// bounds[i] 为第 i 段的起始位置，每次合并优先级最高 (rank 最小) 的相邻两段
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		minRank, minIdx := math.MaxInt, -1
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := e.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < minRank {
+				minRank, minIdx = rank, i
+			}
+		}
+		if minIdx == -1 {
+			break
+		}
+		bounds = append(bounds[:minIdx+1], bounds[minIdx+2:]...)
+	}
+	for i := 0; i+1 < len(bounds); i++ {
+		emit(e.ranks[piece[bounds[i]:bounds[i+1]]]) // 词表包含全部单字节，加载时已校验
+	}
+}
+
+// parseVocab 解析 tiktoken 格式的词表：每行为 "base64(字节序列) rank"
+func parseVocab(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int, 1<<17)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		token, rankStr, ok := bytes.Cut(text, []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d: missing rank", InvalidVocabErr, line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(token))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", InvalidVocabErr, line, err)
+		}
+		rank, err := strconv.Atoi(string(rankStr))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", InvalidVocabErr, line, err)
+		}
+		ranks[string(decoded)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", InvalidVocabErr, err)
+	}
+	for b := 0; b < 256; b++ { // 保证任意字节序列都能被编码
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("%w: missing single byte token 0x%02x", InvalidVocabErr, b)
+		}
+	}
+	return ranks, nil
+}
+
+func newEncoding(name string, ranks map[string]int) (*Encoding, error) {
+	spec, ok := encodingSpecs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", UnknownEncodingErr, name)
+	}
+	decoder := make(map[int]string, len(ranks)+len(spec.specials))
+	for token, rank := range ranks {
+		decoder[rank] = token
+	}
+	for token, rank := range spec.specials {
+		decoder[rank] = token
+	}
+	return &Encoding{
+		name:     name,
+		split:    spec.split,
+		ranks:    ranks,
+		decoder:  decoder,
+		specials: spec.specials,
+	}, nil
+}
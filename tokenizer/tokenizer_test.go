@@ -0,0 +1,112 @@
+// Package tokenizer
+// @Author Clover
+// @Data 2026/10/18 下午11:20:00
+// @Desc 分词器测试 (使用构造的小词表)
+package tokenizer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testVocab 全部单字节 (rank 为字节值) 加上若干合并结果
+func testVocab(merges ...string) string {
+	var sb strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	for i, merge := range merges {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(merge)), 256+i)
+	}
+	return sb.String()
+}
+
+func TestEncoding_Encode(t *testing.T) {
+	enc, err := Load(Cl100kBase, strings.NewReader(testVocab("he", "ll", "hello", " w", "or", " wor", " world")))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{name: "whole piece", text: "hello", want: []int{258}},
+		{name: "merge by rank", text: "hell", want: []int{256, 257}},
+		{name: "multiple pieces", text: "hello world", want: []int{258, 262}},
+		{name: "bytes fallback", text: "hi", want: []int{'h', 'i'}},
+		{name: "utf-8 bytes", text: "你", want: []int{0xe4, 0xbd, 0xa0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := enc.Encode(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Encode() = %v, want %v", got, tt.want)
+			}
+			if n := enc.Count(tt.text); n != len(tt.want) {
+				t.Errorf("Count() = %d, want %d", n, len(tt.want))
+			}
+			if decoded := enc.Decode(got); decoded != tt.text {
+				t.Errorf("Decode() = %q, want %q", decoded, tt.text)
+			}
+		})
+	}
+	if token, ok := enc.SpecialToken("<|endoftext|>"); !ok || token != 100257 {
+		t.Errorf("SpecialToken() = %d, %v", token, ok)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	text := "Hello world's 1234567 \n\n  foo!!\n 你好，世界"
+	tests := []struct {
+		encoding string
+		text     string
+		want     []string
+	}{
+		{encoding: Cl100kBase, text: text, want: []string{"Hello", " world", "'s", " ", "123", "456", "7", " \n\n", " ", " foo", "!!\n", " 你好", "，世界"}},
+		{encoding: O200kBase, text: text, want: []string{"Hello", " world's", " ", "123", "456", "7", " \n\n", " ", " foo", "!!\n", " 你好", "，世界"}},
+		{encoding: Cl100kBase, text: "I'LL go  ", want: []string{"I", "'LL", " go", "  "}},
+		{encoding: O200kBase, text: "HELLO World HTTPServer", want: []string{"HELLO", " World", " HTTPServer"}},
+		{encoding: O200kBase, text: "a/b//\n", want: []string{"a", "/b", "//\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoding+" "+tt.text, func(t *testing.T) {
+			if got := encodingSpecs[tt.encoding].split(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEncoding(t *testing.T) {
+	dir := t.TempDir()
+	SetVocabDir(dir)
+	defer SetVocabDir("")
+
+	if _, err := GetEncoding(O200kBase); !errors.Is(err, VocabNotFoundErr) {
+		t.Fatalf("GetEncoding() missing vocab error = %v, want VocabNotFoundErr", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, O200kBase+vocabFileExt), []byte(testVocab("ab")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	SetVocabDir(dir) // 清除加载失败的记录
+	enc, err := ForModel("gpt-4o-mini")
+	if err != nil || enc.Name() != O200kBase || enc.Count("ab") != 1 {
+		t.Fatalf("ForModel() = %v, %v", enc, err)
+	}
+	if _, err = GetEncoding("p50k_base"); !errors.Is(err, UnknownEncodingErr) {
+		t.Errorf("GetEncoding() unknown error = %v", err)
+	}
+	if _, err = Load(Cl100kBase, strings.NewReader("YQ== 0\n")); !errors.Is(err, InvalidVocabErr) {
+		t.Errorf("Load() incomplete vocab error = %v, want InvalidVocabErr", err)
+	}
+	if name, ok := EncodingNameForModel("gpt-3.5-turbo"); !ok || name != Cl100kBase {
+		t.Errorf("EncodingNameForModel() = %s, %v", name, ok)
+	}
+}
@@ -0,0 +1,211 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午7:20:00
+// @Desc 多轮工具调用：执行模型请求的工具并携带结果继续请求，直到模型给出最终回答
+package ai_sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/config"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+const toolChoiceNone = "none" // 禁止模型继续调用工具
+
+var toolLoopExhaustedErr = errors.New("tool loop budget exhausted") // 达到上限后模型仍要求调用工具
+
+// answerFunc 使用 ctx 发起一次模型请求，返回第一个 choice 的回答与结束原因
+type answerFunc func(ctx context.Context, req Request) (answer Message, finishReason string, err error)
+
+// SetToolLoopLimit 设置单次对话的工具调用上限：maxRounds 为最多执行的工具轮数，maxDuration 为最长耗时
+// 传入 0 表示使用会话主体配置中的 max_tool_rounds / tool_loop_timeout
+func (s *Session) SetToolLoopLimit(maxRounds int, maxDuration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxToolRounds = maxRounds
+	s.toolLoopTimeout = maxDuration
+}
+
+// toolLoopLimit 获取生效的工具调用上限，maxDuration 为 0 表示不限制耗时
+func (s *Session) toolLoopLimit() (maxRounds int, maxDuration time.Duration) {
+	cfg := s.cfg()
+	maxRounds = cfg.MaxToolRounds
+	maxDuration = time.Duration(cfg.ToolLoopTimeout) * time.Second
+	if s != nil {
+		s.mu.RLock()
+		if s.maxToolRounds > 0 {
+			maxRounds = s.maxToolRounds
+		}
+		if s.toolLoopTimeout > 0 {
+			maxDuration = s.toolLoopTimeout
+		}
+		s.mu.RUnlock()
+	}
+	if maxRounds <= 0 {
+		maxRounds = config.DefaultMaxToolRounds
+	}
+	return maxRounds, maxDuration
+}
+
+// runToolLoop 发起请求，模型要求调用工具时执行工具并携带结果继续请求，直到模型给出最终回答
+// 返回本轮对话产生的全部消息 (含中间的 tool_calls 回答与 tool 消息)，最后一条为最终回答
+// 工具轮次的模型请求与工具调用共用耗时上限的 deadline；执行的工具轮数达到上限或耗时超出上限后，
+// 以 tool_choice=none 使用 ctx 再请求一次，要求模型根据已有结果直接回答
+func (s *sessionInfo) runToolLoop(ctx context.Context, msgs answerList, tools *[]Tool, send answerFunc) (retAnswers answerList, err error) {
+	maxRounds, maxDuration := s.session.toolLoopLimit()
+	start := time.Now()
+	loopCtx := ctx
+	if maxDuration > 0 {
+		var cancel context.CancelFunc
+		loopCtx, cancel = context.WithTimeout(ctx, maxDuration)
+		defer cancel()
+	}
+	timedOut := func() bool { return loopCtx.Err() != nil && ctx.Err() == nil } // 仅耗时上限到期，调用方的 ctx 仍有效
+	finalAnswer := func(req *Request, round int) {
+		log.Warn().Str("session", s.sessionId).Int("rounds", round).Dur("elapsed", time.Since(start)).
+			Msg("tool loop budget exhausted, asking for a final answer")
+		req.ToolChoice = toolChoiceNone
+	}
+	req := s.newRequest(msgs, tools)
+	for round := 0; ; round++ {
+		var (
+			answer       Message
+			finishReason string
+			toolMsgs     []Message
+		)
+		if req.ToolChoice == toolChoiceNone {
+			answer, finishReason, err = send(ctx, req)
+		} else if answer, finishReason, err = send(loopCtx, req); err != nil && timedOut() { // 模型请求超出耗时上限
+			finalAnswer(&req, round)
+			answer, finishReason, err = send(ctx, req)
+		}
+		if err != nil {
+			return retAnswers, fmt.Errorf("tool loop round %d: %w", round, err)
+		}
+		if finishReason != ToolsCallFinishReason || len(answer.ToolCalls) == 0 { // 最终回答
+			answer.ToolCalls = nil
+			return append(retAnswers, answer), nil
+		}
+		if req.ToolChoice == toolChoiceNone {
+			return retAnswers, fmt.Errorf("tool loop round %d: %w", round, toolLoopExhaustedErr)
+		}
+		retAnswers = append(retAnswers, answer) // tool answer
+		toolMsgs, err = s.callTools(loopCtx, answer.ToolCalls)
+		if err != nil && !timedOut() {
+			return retAnswers, fmt.Errorf("tool loop round %d: %w", round, err)
+		}
+		retAnswers = append(retAnswers, toolMsgs...) // 将tools答案添加回 msg—history，超时的工具以错误信息告知模型
+		// 携带tools上下文继续请求
+		req = s.newRequest(append(append(answerList(nil), msgs...), retAnswers...), tools)
+		if round+1 >= maxRounds || loopCtx.Err() != nil {
+			finalAnswer(&req, round+1)
+		}
+	}
+}
+
+// SetToolExecution 设置同一轮工具调用的并发数与单个工具的超时时间，传入 0 表示使用配置中的 tool_workers / tool_timeout
+// FuncCallInfo.Timeout 优先于此处设置的超时时间
+func (s *Session) SetToolExecution(workers int, timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.toolWorkers = workers
+	s.toolTimeout = timeout
+}
+
+// toolExecution 获取生效的工具并发数与超时时间，timeout 为 0 表示不限制
+func (s *Session) toolExecution() (workers int, timeout time.Duration) {
+	cfg := s.cfg()
+	workers = cfg.ToolWorkers
+	timeout = time.Duration(cfg.ToolTimeout) * time.Second
+	if s != nil {
+		s.mu.RLock()
+		if s.toolWorkers > 0 {
+			workers = s.toolWorkers
+		}
+		if s.toolTimeout > 0 {
+			timeout = s.toolTimeout
+		}
+		s.mu.RUnlock()
+	}
+	if workers <= 0 {
+		workers = config.DefaultToolWorkers
+	}
+	return workers, timeout
+}
+
+// callTools 并发执行一轮中的工具调用，返回与 calls 顺序一致的 tool 消息
+// 单个工具失败 (未注册、超时、返回错误、panic) 时以 {"error": "..."} 的 tool 消息告知模型，不中断本轮对话；
+// 仅 ctx 结束时返回 error，此时仍返回全部 tool 消息，被中断或未开始的调用为错误信息
+func (s *sessionInfo) callTools(ctx context.Context, calls []ToolCall) (toolMsgs []Message, err error) {
+	workers, timeout := s.session.toolExecution()
+	toolMsgs = make([]Message, len(calls))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		acquired := false
+		if ctx.Err() == nil {
+			select {
+			case sem <- struct{}{}:
+				acquired = true
+			case <-ctx.Done():
+			}
+		}
+		if !acquired { // ctx 已结束，不再发起剩余的调用
+			toolMsgs[i] = Message{Role: toolRole, ToolCallID: call.ID, Content: toolErrorContent(call.Function.Name, "call canceled", ctx.Err())}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, call ToolCall) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if !s.toolAllowed(call.Function.Name) {
+				toolMsgs[i] = Message{Role: toolRole, ToolCallID: call.ID, Content: toolErrorContent(call.Function.Name, "call failed", toolNotAllowedErr)}
+				return
+			}
+			toolMsgs[i] = callTool(ctx, call, timeout)
+		}(i, call)
+	}
+	wg.Wait()
+	if err = ctx.Err(); err != nil {
+		return toolMsgs, fmt.Errorf("tool calls canceled: %w", err)
+	}
+	return toolMsgs, nil
+}
+
+// callTool 执行单个工具调用，失败时返回错误信息作为 tool 消息内容
+func callTool(ctx context.Context, call ToolCall, timeout time.Duration) (toolMsg Message) {
+	name := call.Function.Name
+	toolMsg = Message{Role: toolRole, ToolCallID: call.ID}
+	defer func() {
+		if r := recover(); r != nil {
+			toolMsg.Content = toolErrorContent(name, "call failed", fmt.Errorf("panic: %v", r))
+		}
+	}()
+	callInfo := FuncRegister.GetCallInfo(name)
+	if callInfo == nil {
+		toolMsg.Content = toolErrorContent(name, "call failed", funcNotFoundErr)
+		return toolMsg
+	}
+	if callInfo.Timeout > 0 {
+		timeout = callInfo.Timeout
+	}
+	callCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	msg, err := callInfo.CallWithContext(callCtx, call.ID, call.Function.Arguments) // 请求外部函数
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timeout after %s", timeout)
+		}
+		toolMsg.Content = toolErrorContent(name, "call failed", err)
+		return toolMsg
+	}
+	return msg
+}
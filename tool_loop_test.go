@@ -0,0 +1,116 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/18 下午8:10:00
+// @Desc 工具并发执行测试
+package ai_sdk
+
+import (
+	"context"
+	"errors"
+	"github.com/Clov614/go-ai-sdk/global"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testToolFunc 按参数执行不同行为的工具：sleep 阻塞至 ctx 结束，fail 返回错误，panic 触发 panic
+type testToolFunc struct {
+	calls, inFlight, maxInFlight int32
+}
+
+func (f *testToolFunc) Call(params string) (string, error) {
+	return f.CallWithContext(context.Background(), params)
+}
+
+func (f *testToolFunc) CallWithContext(ctx context.Context, params string) (string, error) {
+	atomic.AddInt32(&f.calls, 1)
+	n := atomic.AddInt32(&f.inFlight, 1)
+	defer atomic.AddInt32(&f.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&f.maxInFlight)
+		if n <= max || atomic.CompareAndSwapInt32(&f.maxInFlight, max, n) {
+			break
+		}
+	}
+	switch params {
+	case "sleep":
+		<-ctx.Done()
+		return "", ctx.Err()
+	case "fail":
+		return "", errors.New("boom")
+	case "panic":
+		panic("oops")
+	}
+	time.Sleep(20 * time.Millisecond) // 保证并发执行时存在重叠
+	return `"` + params + `"`, nil
+}
+
+func Test_sessionInfo_callTools(t *testing.T) {
+	tool := &testToolFunc{}
+	if err := FuncRegister.Register(&FuncCallInfo{
+		Function: Function{Name: "parallel_tool", Parameters: FunctionParameter{Type: global.ObjType}},
+		CallFunc: tool,
+		Timeout:  50 * time.Millisecond,
+	}, nil); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	call := func(id, name, params string) ToolCall {
+		return ToolCall{ID: id, Type: defaultFuncType, Function: FunctionCall{Name: name, Arguments: params}}
+	}
+	calls := []ToolCall{ // tool 消息需与 tool_calls 的顺序一致，而不是按 ID 排序
+		call("call_10", "parallel_tool", "sleep"),
+		call("call_9", "parallel_tool", "e"),
+		call("call_3", "parallel_tool", "fail"),
+		call("call_2", "missing_tool", "{}"),
+		call("call_1", "parallel_tool", "panic"),
+		call("call_0", "parallel_tool", "a"),
+	}
+	s := NewSession("并发测试", 2)
+	s.SetToolExecution(2, time.Second)
+	info := s.GetSession("parallel", nil)
+
+	msgs, err := info.callTools(context.Background(), calls)
+	if err != nil {
+		t.Fatalf("callTools() error = %v", err)
+	}
+	want := []struct{ id, content string }{
+		{"call_10", `{"error":"parallel_tool call failed: timeout after 50ms"}`},
+		{"call_9", `"e"`},
+		{"call_3", `{"error":"parallel_tool call failed: FuncCallInfo.CallFunc.Call error: boom"}`},
+		{"call_2", `{"error":"missing_tool call failed: function not registered"}`},
+		{"call_1", `{"error":"parallel_tool call failed: panic: oops"}`},
+		{"call_0", `"a"`},
+	}
+	if len(msgs) != len(want) {
+		t.Fatalf("callTools() = %+v", msgs)
+	}
+	for i, w := range want {
+		if msgs[i].Role != toolRole || msgs[i].ToolCallID != w.id || msgs[i].Content != w.content {
+			t.Errorf("callTools()[%d] = %+v, want %s %s", i, msgs[i], w.id, w.content)
+		}
+	}
+	if max := atomic.LoadInt32(&tool.maxInFlight); max != 2 {
+		t.Errorf("max in-flight = %d, want 2", max)
+	}
+
+	// 取消后不再发起等待并发名额的调用
+	s.SetToolExecution(1, time.Second)
+	before := atomic.LoadInt32(&tool.calls)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	msgs, err = info.callTools(ctx, []ToolCall{
+		call("call_a", "parallel_tool", "sleep"),
+		call("call_b", "parallel_tool", "b"),
+		call("call_c", "parallel_tool", "c"),
+	})
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "canceled") {
+		t.Errorf("callTools() canceled error = %v, want context.Canceled", err)
+	}
+	if n := atomic.LoadInt32(&tool.calls) - before; n != 1 {
+		t.Errorf("tool calls after cancel = %d, want 1", n)
+	}
+	if len(msgs) != 3 || msgs[2].ToolCallID != "call_c" || !strings.Contains(msgs[2].Content, "call canceled") {
+		t.Errorf("callTools() canceled = %+v, want canceled results for calls not started", msgs)
+	}
+}
@@ -0,0 +1,153 @@
+// Package ai_sdk
+// @Author Clover
+// @Data 2026/10/19 上午4:40:00
+// @Desc 每个 api 配置在构造客户端时创建独立的连接池 (http.Transport)，按配置设置代理与 TLS，请求过程中不再修改
+package ai_sdk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/Clov614/go-ai-sdk/config"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var transportErr = errors.New("invalid transport config") // api 配置中的代理或 TLS 设置不合法
+
+// endpoint 单个 api 配置的 http.Client 与运行状态，配置不合法时 err 不为空，请求时跳过该配置
+type endpoint struct {
+	url    string
+	client *http.Client
+	err    error
+	health breaker     // api 地址的熔断器
+	keys   []*keyState // 与 AuthList 一一对应
+}
+
+// keyState 密钥的运行状态
+type keyState struct {
+	auth   string
+	health breaker      // 密钥的熔断器
+	load   keyLoad      // 进行中的请求数与延迟
+	limit  *rateLimiter // 客户端限流，未设置时为 nil
+	// 隔离状态
+	quarantine keyQuarantine
+}
+
+// newKeyStates 为 api 配置的每个密钥创建运行状态
+func newKeyStates(apiCfg config.APIConfig) []*keyState {
+	keys := make([]*keyState, len(apiCfg.AuthList))
+	for i, auth := range apiCfg.AuthList {
+		keys[i] = &keyState{auth: auth, limit: newRateLimiter(keyRateLimit(apiCfg, auth))}
+	}
+	return keys
+}
+
+// key 第 j 个密钥的运行状态，构造后追加的密钥返回 nil
+func (ep *endpoint) key(j int) *keyState {
+	if ep == nil || j >= len(ep.keys) {
+		return nil
+	}
+	return ep.keys[j]
+}
+
+// buildEndpoints 为每个 api 配置创建 http.Client，返回全部配置错误
+func (a *AIClient) buildEndpoints() error {
+	a.endpoints = make([]endpoint, len(a.ApiCfgList))
+	var errs []error
+	for i, apiCfg := range a.ApiCfgList {
+		a.endpoints[i].url = apiCfg.Url
+		a.endpoints[i].keys = newKeyStates(apiCfg)
+		a.tokenLimited = a.tokenLimited || tpmLimited(apiCfg)
+		client := *a.client
+		if !a.customTransport {
+			transport, err := newTransport(apiCfg)
+			if err != nil {
+				err = fmt.Errorf("api config %d (%s): %w", i, apiCfg.Url, err)
+				log.Error().Err(err).Msg("build transport failed")
+				a.endpoints[i].err = err
+				errs = append(errs, err)
+				continue
+			}
+			client.Transport = transport
+		}
+		a.endpoints[i].client = &client
+	}
+	return errors.Join(errs...)
+}
+
+// endpointAt 第 i 个 api 配置的运行状态，构造后追加的 api 配置返回 nil
+func (a AIClient) endpointAt(i int) *endpoint {
+	if i >= len(a.endpoints) {
+		return nil
+	}
+	return &a.endpoints[i]
+}
+
+// httpClient 获取第 i 个 api 配置的 http.Client，流式请求不设置整体超时 (超时由 doRequest 控制在响应头阶段)
+func (a AIClient) httpClient(i int, stream bool) (*http.Client, error) {
+	client := a.client
+	if i < len(a.endpoints) { // 构造后追加的 api 配置使用默认 client
+		if err := a.endpoints[i].err; err != nil {
+			return nil, err
+		}
+		client = a.endpoints[i].client
+	}
+	if stream {
+		c := *client
+		c.Timeout = 0
+		client = &c
+	}
+	return client, nil
+}
+
+// newTransport 根据 api 配置创建 http.Transport：代理支持 http/https/socks5，可追加 CA 证书或跳过证书校验
+// 未设置代理时使用环境变量中的代理设置 (与 http.DefaultTransport 一致)
+func newTransport(apiCfg config.APIConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if apiCfg.ProxyAddr != "" {
+		proxyAddr := apiCfg.ProxyAddr
+		if !strings.Contains(proxyAddr, "://") { // 未写协议时按 http 代理处理
+			proxyAddr = ensurePrefix(proxyAddr)
+		}
+		proxy, err := url.Parse(proxyAddr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: proxy %q: %w", transportErr, apiCfg.ProxyAddr, err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("%w: unsupported proxy scheme %q", transportErr, proxy.Scheme)
+		}
+		if proxy.Host == "" {
+			return nil, fmt.Errorf("%w: proxy %q has no host", transportErr, apiCfg.ProxyAddr)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if apiCfg.CACertFile != "" || apiCfg.InsecureSkipVerify {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: apiCfg.InsecureSkipVerify, // nolint:gosec 仅在配置中显式开启
+		}
+		if apiCfg.CACertFile != "" {
+			pem, err := os.ReadFile(apiCfg.CACertFile)
+			if err != nil {
+				return nil, fmt.Errorf("%w: read ca cert: %w", transportErr, err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("%w: no certificate found in %s", transportErr, apiCfg.CACertFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
@@ -0,0 +1,126 @@
+package ai_sdk
+
+import (
+	"encoding/pem"
+	"errors"
+	"github.com/Clov614/go-ai-sdk/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const okBody = `{"id":"chatcmpl-test","choices":[{"message":{"role":"assistant","content":"ok"}}]}`
+
+// newProxyServer 模拟 http 代理：记录请求的目标 host，按 status 响应
+func newProxyServer(t *testing.T, status int) (*httptest.Server, *sync.Map) {
+	t.Helper()
+	hosts := &sync.Map{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts.Store(r.URL.Host, true)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(okBody))
+	}))
+	t.Cleanup(server.Close)
+	return server, hosts
+}
+
+func TestAIClient_PerConfigProxy(t *testing.T) {
+	deny, denyHosts := newProxyServer(t, http.StatusUnauthorized)
+	allow, allowHosts := newProxyServer(t, http.StatusOK)
+	a := NewAIClient([]config.APIConfig{
+		{Url: "http://a.invalid", AuthList: []string{"sk-a"}, ProxyAddr: deny.Listener.Addr().String()},
+		{Url: "http://b.invalid", AuthList: []string{"sk-b"}, ProxyAddr: allow.URL},
+	}, config.DefaultModel, config.DefaultEndPoint, 10)
+
+	var wg sync.WaitGroup
+	var failed int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := a.Send(Request{Messages: []Message{{Role: userRole, Content: "hi"}}}); err != nil {
+				atomic.AddInt32(&failed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if failed != 0 {
+		t.Fatalf("%d concurrent requests failed", failed)
+	}
+	for proxy, hosts := range map[string]*sync.Map{"a.invalid": denyHosts, "b.invalid": allowHosts} {
+		n := 0
+		hosts.Range(func(key, _ any) bool {
+			if key != proxy {
+				t.Errorf("proxy for %s received request for %v", proxy, key)
+			}
+			n++
+			return true
+		})
+		if n != 1 {
+			t.Errorf("proxy for %s received %d hosts, want 1", proxy, n)
+		}
+	}
+}
+
+func TestAIClient_TLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(okBody))
+	}))
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		apiCfg  config.APIConfig
+		wantErr bool
+	}{
+		{name: "untrusted certificate", apiCfg: config.APIConfig{}, wantErr: true},
+		{name: "custom ca", apiCfg: config.APIConfig{CACertFile: caFile}},
+		{name: "insecure skip verify", apiCfg: config.APIConfig{InsecureSkipVerify: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.apiCfg.Url, tt.apiCfg.AuthList = server.URL, []string{"sk-test"}
+			a, err := NewClient([]config.APIConfig{tt.apiCfg})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			_, err = a.Send(Request{Messages: []Message{{Role: userRole, Content: "hi"}}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewClient_InvalidTransport(t *testing.T) {
+	badPEM := filepath.Join(t.TempDir(), "bad.pem")
+	if err := os.WriteFile(badPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		apiCfg config.APIConfig
+	}{
+		{name: "unsupported proxy scheme", apiCfg: config.APIConfig{ProxyAddr: "ftp://127.0.0.1:21"}},
+		{name: "socks5 without host", apiCfg: config.APIConfig{ProxyAddr: "socks5://"}},
+		{name: "missing ca file", apiCfg: config.APIConfig{CACertFile: filepath.Join(t.TempDir(), "none.pem")}},
+		{name: "invalid ca file", apiCfg: config.APIConfig{CACertFile: badPEM}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.apiCfg.Url, tt.apiCfg.AuthList = "http://127.0.0.1", []string{"sk-test"}
+			_, err := NewClient([]config.APIConfig{tt.apiCfg})
+			if !errors.Is(err, clientOptionErr) || !errors.Is(err, transportErr) {
+				t.Errorf("NewClient() error = %v, want transportErr", err)
+			}
+		})
+	}
+}